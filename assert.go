@@ -0,0 +1,129 @@
+package main
+
+import (
+	"fmt"
+	"text/template"
+)
+
+// AssertStyle is the style of comparisons in generated
+// table-driven tests.
+type AssertStyle int
+
+const (
+	// StdAssert uses reflect.DeepEqual and t.Errorf.
+	StdAssert AssertStyle = iota
+
+	// TestifyAssert uses github.com/stretchr/testify.
+	TestifyAssert
+
+	// GoCmpAssert uses github.com/google/go-cmp/cmp.
+	GoCmpAssert
+
+	// QuicktestAssert uses github.com/frankban/quicktest.
+	QuicktestAssert
+)
+
+// ParseAssertStyle returns AssertStyle from its flag value.
+func ParseAssertStyle(s string) (AssertStyle, error) {
+	switch s {
+	case "", "std":
+		return StdAssert, nil
+	case "testify":
+		return TestifyAssert, nil
+	case "go-cmp":
+		return GoCmpAssert, nil
+	case "quicktest":
+		return QuicktestAssert, nil
+	default:
+		return StdAssert, fmt.Errorf("unknown assert style %q (must be std, testify, go-cmp or quicktest)", s)
+	}
+}
+
+// assertImports are packages used by each style. They are given to
+// goimports explicitly since it can't find some of them by identifiers
+// (e.g., `qt` for quicktest or `cmp` which is also in standard library).
+var assertImports = map[AssertStyle][][2]string{
+	StdAssert: {
+		{"", "reflect"},
+	},
+	TestifyAssert: {
+		{"", "github.com/stretchr/testify/assert"},
+		{"", "github.com/stretchr/testify/require"},
+	},
+	GoCmpAssert: {
+		{"", "github.com/google/go-cmp/cmp"},
+	},
+	QuicktestAssert: {
+		{"qt", "github.com/frankban/quicktest"},
+	},
+}
+
+// assertTmpls define "setup" (statements before the comparisons),
+// "errCheck" (checking error result with wantErr) and "compare"
+// (comparing a result with its expected value) of each style.
+var assertTmpls = map[AssertStyle]string{
+	StdAssert: `
+{{- define "setup" }}{{ end }}
+{{- define "errCheck" }}
+			if (err != nil) != tt.wantErr {
+				t.Errorf("{{ .Name }}() error = %v, wantErr %v", err, tt.wantErr)
+			}
+{{- end }}
+{{- define "compare" }}
+			if !reflect.DeepEqual({{ .Got }}, tt.{{ .Want }}) {
+				t.Errorf("{{ .Name }}() {{ .Got }} = %v, want %v", {{ .Got }}, tt.{{ .Want }})
+			}
+{{- end }}`,
+
+	TestifyAssert: `
+{{- define "setup" }}{{ end }}
+{{- define "errCheck" }}
+			if tt.wantErr {
+				require.Error(t, err)
+			} else {
+				require.NoError(t, err)
+			}
+{{- end }}
+{{- define "compare" }}
+			assert.Equal(t, tt.{{ .Want }}, {{ .Got }})
+{{- end }}`,
+
+	GoCmpAssert: `
+{{- define "setup" }}{{ end }}
+{{- define "errCheck" }}
+			if (err != nil) != tt.wantErr {
+				t.Errorf("{{ .Name }}() error = %v, wantErr %v", err, tt.wantErr)
+			}
+{{- end }}
+{{- define "compare" }}
+			if diff := cmp.Diff(tt.{{ .Want }}, {{ .Got }}); diff != "" {
+				t.Errorf("{{ .Name }}() {{ .Got }} mismatch (-want +got):\n%s", diff)
+			}
+{{- end }}`,
+
+	QuicktestAssert: `
+{{- define "setup" }}
+			c := qt.New(t)
+{{- end }}
+{{- define "errCheck" }}
+			if tt.wantErr {
+				c.Assert(err, qt.IsNotNil)
+			} else {
+				c.Assert(err, qt.IsNil)
+			}
+{{- end }}
+{{- define "compare" }}
+			c.Assert({{ .Got }}, qt.DeepEquals, tt.{{ .Want }})
+{{- end }}`,
+}
+
+// tableTestTmpls are table-driven test templates of each style.
+var tableTestTmpls = make(map[AssertStyle]*template.Template)
+
+func init() {
+	for style, defs := range assertTmpls {
+		tmpl := template.Must(tableTestBodyTmpl.Clone())
+		tmpl = template.Must(tmpl.Parse(goldenTmpl))
+		tableTestTmpls[style] = template.Must(tmpl.Parse(defs))
+	}
+}
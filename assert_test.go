@@ -0,0 +1,64 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParseAssertStyle(t *testing.T) {
+	cases := []struct {
+		in      string
+		success bool
+		style   AssertStyle
+	}{
+		{"", true, StdAssert},
+		{"std", true, StdAssert},
+		{"testify", true, TestifyAssert},
+		{"go-cmp", true, GoCmpAssert},
+		{"quicktest", true, QuicktestAssert},
+		{"gomega", false, StdAssert},
+	}
+
+	for _, tc := range cases {
+		style, err := ParseAssertStyle(tc.in)
+		if tc.success != (err == nil) {
+			t.Fatalf("%q: expected success to be %t, err: %v", tc.in, tc.success, err)
+		}
+
+		if style != tc.style {
+			t.Errorf("%q: expected %d to eq %d", tc.in, style, tc.style)
+		}
+	}
+}
+
+func TestTestBuilder_testify(t *testing.T) {
+	src := `package p
+
+func Parse(s string) (int, error) { return 0, nil }
+`
+	goFile, err := parse("p.go", strings.NewReader(src))
+	if err != nil {
+		t.Fatalf("parse failed: %s", err)
+	}
+
+	builder := &testBuilder{table: true, style: TestifyAssert}
+	res, err := builder.build("TestParse", funcTarget(goFile.Funcs[0]))
+	if err != nil {
+		t.Fatalf("build failed: %s", err)
+	}
+
+	expected := `			got, err := Parse(tt.args.s)
+			if tt.wantErr {
+				require.Error(t, err)
+			} else {
+				require.NoError(t, err)
+			}
+			if tt.wantErr {
+				return
+			}
+			assert.Equal(t, tt.want, got)
+`
+	if !strings.Contains(string(res), expected) {
+		t.Errorf("expected %q to contain %q", res, expected)
+	}
+}
@@ -20,27 +20,45 @@ func DebugAst(fset *token.FileSet, x interface{}) {
 // NewTestFuncDecl creates a new FuncDecl for starndard testing
 // without position.
 func NewTestFuncDecl(name string) *ast.FuncDecl {
+	return newTestingFuncDecl(name, "t", "T")
+}
+
+// NewBenchmarkFuncDecl creates a new FuncDecl for benchmark
+// (func(b *testing.B)) without position.
+func NewBenchmarkFuncDecl(name string) *ast.FuncDecl {
+	return newTestingFuncDecl(name, "b", "B")
+}
+
+// NewFuzzFuncDecl creates a new FuncDecl for fuzz test
+// (func(f *testing.F)) without position.
+func NewFuzzFuncDecl(name string) *ast.FuncDecl {
+	return newTestingFuncDecl(name, "f", "F")
+}
+
+// newTestingFuncDecl creates a new FuncDecl whose param is
+// a pointer of testing package type (e.g., t *testing.T).
+func newTestingFuncDecl(name, varName, typeName string) *ast.FuncDecl {
 
 	ident := ast.NewIdent(name)
 	ident.Obj = ast.NewObj(ast.Fun, name)
 
-	identVarT := ast.NewIdent("t")
-	identVarT.Obj = ast.NewObj(ast.Var, "t")
+	identVar := ast.NewIdent(varName)
+	identVar.Obj = ast.NewObj(ast.Var, varName)
 
 	// params are params for func
 	params := &ast.FieldList{
 		List: []*ast.Field{
 			{
-				// t
+				// e.g., t
 				Names: []*ast.Ident{
-					identVarT,
+					identVar,
 				},
 
-				// *testing.T
+				// e.g., *testing.T
 				Type: &ast.StarExpr{
 					X: &ast.SelectorExpr{
 						X:   ast.NewIdent("testing"),
-						Sel: ast.NewIdent("T"),
+						Sel: ast.NewIdent(typeName),
 					},
 				},
 			},
@@ -57,3 +75,21 @@ func NewTestFuncDecl(name string) *ast.FuncDecl {
 		Body: &ast.BlockStmt{},
 	}
 }
+
+// NewExampleFuncDecl creates a new FuncDecl for example function
+// (which has no params) without position.
+func NewExampleFuncDecl(name string) *ast.FuncDecl {
+
+	ident := ast.NewIdent(name)
+	ident.Obj = ast.NewObj(ast.Fun, name)
+
+	funcType := &ast.FuncType{
+		Params: &ast.FieldList{},
+	}
+
+	return &ast.FuncDecl{
+		Name: ident,
+		Type: funcType,
+		Body: &ast.BlockStmt{},
+	}
+}
@@ -0,0 +1,67 @@
+package main
+
+import (
+	"bytes"
+	"text/template"
+)
+
+var (
+	defaultExpectBenchmarkFuncTmpl       = "Benchmark{{ title .Name }}"
+	defaultExpectBenchmarkFuncMethodTmpl = "Benchmark{{ title .RecvName }}_{{ title .Name }}"
+)
+
+// benchmarkBodyTmpl is the body of generated benchmark function. It calls
+// the target with zero values in the benchmark loop. b.Loop is used
+// when the module's Go version supports it (Go 1.24 or later).
+var benchmarkBodyTmpl = template.Must(template.New("benchmark").Parse(`
+{{- range .Target.Vars }}
+	{{ .Decl }}
+{{- end }}
+{{- range .Target.ChanStmts }}
+	{{ . }}
+{{- end }}
+{{- if .Loop }}
+	for b.Loop() {
+		{{ .Target.Call }}
+	}
+{{- else }}
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		{{ .Target.Call }}
+	}
+{{- end }}
+`))
+
+// benchmarkBuilder returns declBuilder which builds a benchmark function.
+func benchmarkBuilder(loop bool) declBuilder {
+	return func(name string, t *target) ([]byte, error) {
+		t.reserve("b", "i")
+
+		data := struct {
+			Target *target
+			Loop   bool
+		}{
+			Target: t,
+			Loop:   loop,
+		}
+
+		var buf bytes.Buffer
+		if err := benchmarkBodyTmpl.Execute(&buf, data); err != nil {
+			return nil, err
+		}
+
+		return formatFuncDecl(NewBenchmarkFuncDecl(name), buf.String())
+	}
+}
+
+// benchmarkKind returns funcKind which generates benchmark functions.
+// goVersion is the Go version of the module.
+func benchmarkKind(goVersion string, includeUnexported bool) *funcKind {
+	return &funcKind{
+		name:              "Benchmark",
+		funcTmpl:          defaultExpectBenchmarkFuncTmpl,
+		methodTmpl:        defaultExpectBenchmarkFuncMethodTmpl,
+		build:             benchmarkBuilder(goVersionAtLeast(goVersion, "1.24")),
+		includeUnexported: includeUnexported,
+	}
+}
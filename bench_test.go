@@ -0,0 +1,57 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestBenchmarkBuilder(t *testing.T) {
+	src := `package p
+
+func Sum(a, b int) int { return a + b }
+`
+	goFile, err := parse("p.go", strings.NewReader(src))
+	if err != nil {
+		t.Fatalf("parse failed: %s", err)
+	}
+
+	cases := []struct {
+		loop     bool
+		expected string
+	}{
+		{
+			loop: false,
+			expected: `func BenchmarkSum(b *testing.B) {
+	var a int
+	var arg1 int
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		Sum(a, arg1)
+	}
+}`,
+		},
+
+		{
+			loop: true,
+			expected: `func BenchmarkSum(b *testing.B) {
+	var a int
+	var arg1 int
+	for b.Loop() {
+		Sum(a, arg1)
+	}
+}`,
+		},
+	}
+
+	for _, tc := range cases {
+		build := benchmarkBuilder(tc.loop)
+		res, err := build("BenchmarkSum", funcTarget(goFile.Funcs[0]))
+		if err != nil {
+			t.Fatalf("build failed: %s", err)
+		}
+
+		if string(res) != tc.expected {
+			t.Errorf("expected %q to eq %q", res, tc.expected)
+		}
+	}
+}
@@ -0,0 +1,75 @@
+package main
+
+import (
+	"fmt"
+	"go/ast"
+)
+
+// processCheck reports the functions and methods of the source file whose
+// tests are missing (see -check). It returns ExitCodeError if there is any.
+// Only the missing tests are looked up (orphaned ones are not checked).
+func (cli *CLI) processCheck(srcPath, testPath string, opts *generateOpts) int {
+	goFile, err := parseFileOverlay(srcPath, opts.overlay)
+	if err != nil {
+		cli.reportError(srcPath, fmt.Errorf("failed to parse go file: %w", err))
+		return ExitCodeError
+	}
+	opts.filterChanged(srcPath, goFile)
+
+	goTestFile, err := openTestFile(testPath, goFile.PackageName, opts.overlay)
+	if err != nil {
+		cli.reportError(srcPath, err)
+		return ExitCodeError
+	}
+
+	diffOpts := opts.diffOpts
+	diffOpts.init()
+
+	diffFuncs, err := goFile.diffFuncs(goTestFile, diffOpts)
+	if err != nil {
+		cli.reportError(srcPath, fmt.Errorf("failed to diff source file and test file: %s", err))
+		return ExitCodeError
+	}
+
+	diffMethods, err := goFile.diffMethods(goTestFile, diffOpts)
+	if err != nil {
+		cli.reportError(srcPath, fmt.Errorf("failed to diff source file and test file: %s", err))
+		return ExitCodeError
+	}
+
+	src, err := fmtPath(srcPath)
+	if err != nil {
+		cli.reportError(srcPath, err)
+		return ExitCodeError
+	}
+
+	missing := func(name string, data interface{}, tmpl string, decl *ast.FuncDecl) error {
+		test, err := execFuncTmpl(tmpl, data)
+		if err != nil {
+			return err
+		}
+		line := goFile.FSet.Position(decl.Pos()).Line
+		fmt.Fprintf(cli.outStream, "%s:%d: %s: %s is missing\n", src, line, name, test)
+		return nil
+	}
+
+	for _, fun := range diffFuncs {
+		if err := missing(fun.Name, fun, diffOpts.ExpectTestFuncTmpl, fun.Decl); err != nil {
+			cli.reportError(srcPath, err)
+			return ExitCodeError
+		}
+	}
+
+	for _, method := range diffMethods {
+		name := method.RecvName + "." + method.Name
+		if err := missing(name, method, diffOpts.ExpectTestFuncMethodTmpl, method.Decl); err != nil {
+			cli.reportError(srcPath, err)
+			return ExitCodeError
+		}
+	}
+
+	if len(diffFuncs) > 0 || len(diffMethods) > 0 {
+		return ExitCodeError
+	}
+	return ExitCodeOK
+}
@@ -0,0 +1,59 @@
+package main
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestCLI_processCheck(t *testing.T) {
+	dir, err := ioutil.TempDir("", Name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	src := "package p\n\nfunc Add() {}\n\nfunc Sub() {}\n"
+	testSrc := "package p\n\nimport \"testing\"\n\nfunc TestAdd(t *testing.T) {}\n"
+	srcPath := filepath.Join(dir, "p.go")
+	testPath := filepath.Join(dir, "p_test.go")
+	if err := ioutil.WriteFile(srcPath, []byte(src), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(testPath, []byte(testSrc), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	outStream, errStream := new(bytes.Buffer), new(bytes.Buffer)
+	cli := &CLI{outStream: outStream, errStream: errStream}
+	opts := &generateOpts{diffOpts: &diffOpts{}, check: true}
+
+	if status := cli.processCheck(srcPath, testPath, opts); status != ExitCodeError {
+		t.Errorf("expected %d to eq %d", status, ExitCodeError)
+	}
+
+	path, err := fmtPath(srcPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := path + ":5: Sub: TestSub is missing\n"
+	if got := outStream.String(); got != expected {
+		t.Errorf("expected %q to eq %q", got, expected)
+	}
+
+	// Orphaned tests are not checked.
+	testSrc += "\nfunc TestSub(t *testing.T) {}\n\nfunc TestMul(t *testing.T) {\n\tt.Log()\n}\n"
+	if err := ioutil.WriteFile(testPath, []byte(testSrc), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	outStream.Reset()
+	if status := cli.processCheck(srcPath, testPath, opts); status != ExitCodeOK {
+		t.Errorf("expected %d to eq %d", status, ExitCodeOK)
+	}
+	if got := outStream.String(); got != "" {
+		t.Errorf("expected %q to eq %q", got, "")
+	}
+}
@@ -1,9 +1,11 @@
 package main
 
 import (
-	"bytes"
+	"bufio"
+	"encoding/json"
 	"flag"
 	"fmt"
+	"go/token"
 	"io"
 	"io/ioutil"
 	"os"
@@ -29,6 +31,13 @@ type CLI struct {
 	// outStream and errStream are the stdout and stderr
 	// to write message from the CLI.
 	outStream, errStream io.Writer
+
+	// inStream is the stdin to read messages from language client
+	// (see `gotests lsp`) and selections of -interactive.
+	inStream io.Reader
+
+	// prompt reads selections of -interactive from inStream.
+	prompt *bufio.Reader
 }
 
 // Run invokes the CLI with the given arguments.
@@ -39,6 +48,35 @@ func (cli *CLI) Run(args []string) int {
 		list              bool
 		includeUnexported bool
 		reverse           bool
+		order             string
+		examples          bool
+		bench             bool
+		fuzz              bool
+		table             bool
+		mocks             bool
+		assert            string
+		parallel          bool
+		golden            bool
+		docComment        bool
+		orphans           bool
+		prune             bool
+		rename            string
+		jsonOut           bool
+		line              int
+		overlay           string
+		interactive       bool
+		summary           bool
+		reportFormat      string
+		output            string
+		baseline          string
+		link              string
+		coverprofile      string
+		threshold         float64
+		since             string
+		staged            bool
+		changedFuncs      bool
+		check             bool
+		generate          bool
 		version           bool
 
 		doc bool
@@ -48,7 +86,7 @@ func (cli *CLI) Run(args []string) int {
 	flags := flag.NewFlagSet(Name, flag.ContinueOnError)
 	flags.SetOutput(cli.errStream)
 	flags.Usage = func() {
-		fmt.Fprintf(cli.outStream, helpText)
+		fmt.Fprint(cli.outStream, helpText)
 	}
 
 	flags.BoolVar(&diff, "diff", false, "")
@@ -66,12 +104,69 @@ func (cli *CLI) Run(args []string) int {
 	flags.BoolVar(&includeUnexported, "include-unexported", false, "")
 	flags.BoolVar(&includeUnexported, "i", false, "")
 
+	flags.StringVar(&order, "order", "append", "")
+
+	flags.BoolVar(&examples, "examples", false, "")
+	flags.BoolVar(&bench, "bench", false, "")
+	flags.BoolVar(&fuzz, "fuzz", false, "")
+
+	flags.BoolVar(&table, "table", false, "")
+	flags.BoolVar(&mocks, "mocks", false, "")
+	flags.StringVar(&assert, "assert", "std", "")
+	flags.BoolVar(&parallel, "parallel", false, "")
+	flags.BoolVar(&golden, "golden", false, "")
+	flags.BoolVar(&docComment, "doc", false, "")
+
+	flags.BoolVar(&orphans, "orphans", false, "")
+	flags.BoolVar(&prune, "prune", false, "")
+	flags.StringVar(&rename, "rename", "", "")
+
+	flags.BoolVar(&jsonOut, "json", false, "")
+	flags.IntVar(&line, "line", 0, "")
+	flags.StringVar(&overlay, "overlay", "", "")
+
+	flags.BoolVar(&interactive, "interactive", false, "")
+	flags.BoolVar(&summary, "summary", false, "")
+
+	flags.StringVar(&reportFormat, "report", "", "")
+	flags.StringVar(&output, "o", "", "")
+	flags.StringVar(&baseline, "baseline", "", "")
+	flags.StringVar(&link, "link", "{path}#L{line}", "")
+
+	flags.StringVar(&coverprofile, "coverprofile", "", "")
+	flags.Float64Var(&threshold, "threshold", 50, "")
+
+	flags.StringVar(&since, "since", "", "")
+	flags.BoolVar(&staged, "staged", false, "")
+	flags.BoolVar(&changedFuncs, "changed-funcs", false, "")
+
+	flags.BoolVar(&check, "check", false, "")
+	flags.BoolVar(&generate, "generate", false, "")
+
 	flags.BoolVar(&version, "version", false, "Print version information and quit.")
 	flags.BoolVar(&version, "v", false, "Print version information and quit.")
 
 	// This flag is only for developer to generate godoc via go generate.
 	flags.BoolVar(&doc, "godoc", false, "")
 
+	// `gotests lsp [options]` runs language server instead of
+	// generating tests of PATHs.
+	lsp := len(args) > 1 && args[1] == "lsp"
+	if lsp {
+		args = append(args[:1:1], args[2:]...)
+	}
+
+	// `gotests hook install [options] [PATH ...]` installs git pre-commit
+	// hook which runs gotests with the options on PATHs.
+	hook := len(args) > 1 && args[1] == "hook"
+	if hook {
+		if len(args) < 3 || args[2] != "install" {
+			fmt.Fprintf(cli.errStream, "Invalid arguments: usage: %s hook install [options] [PATH ...]\n", Name)
+			return ExitCodeError
+		}
+		args = append(args[:1:1], args[3:]...)
+	}
+
 	// Parse commandline flag
 	if err := flags.Parse(args[1:]); err != nil {
 		return ExitCodeError
@@ -86,28 +181,142 @@ func (cli *CLI) Run(args []string) int {
 	// Generate godoc (only for developer)
 	if doc {
 		if err := godoc("doc.go"); err != nil {
-			fmt.Fprintf(cli.errStream, "Failed to generate godoc: %s", err)
+			fmt.Fprintf(cli.errStream, "Failed to generate godoc: %s\n", err)
+			return ExitCodeError
+		}
+		return ExitCodeOK
+	}
+
+	if hook {
+		hookArgs := args[1:]
+		if flags.NArg() == 0 {
+			hookArgs = append(hookArgs, ".")
+		}
+
+		path, err := installHook(hookArgs)
+		if err != nil {
+			// Hooks are installed in the repository of the current directory.
+			cli.reportError(".", fmt.Errorf("failed to install hook: %w", err))
 			return ExitCodeError
 		}
+		fmt.Fprintf(cli.outStream, "Installed pre-commit hook: %s\n", path)
 		return ExitCodeOK
 	}
 
 	paths := flags.Args()
-	if len(paths) == 0 {
+
+	// go generate runs commands in the directory of $GOFILE.
+	if len(paths) == 0 && generate && os.Getenv("GOFILE") != "" {
+		paths = []string{"."}
+	}
+
+	if len(paths) == 0 && !lsp {
 		fmt.Fprintf(cli.errStream, "Invalid arguments. You must provide PATHs\n")
 		return ExitCodeError
 	}
 
+	testOrder, err := ParseOrder(order)
+	if err != nil {
+		fmt.Fprintf(cli.errStream, "Invalid arguments: %s\n", err)
+		return ExitCodeError
+	}
+
+	assertStyle, err := ParseAssertStyle(assert)
+	if err != nil {
+		fmt.Fprintf(cli.errStream, "Invalid arguments: %s\n", err)
+		return ExitCodeError
+	}
+
+	renames, err := ParseRenames(rename)
+	if err != nil {
+		fmt.Fprintf(cli.errStream, "Invalid arguments: %s\n", err)
+		return ExitCodeError
+	}
+
+	var overlayFiles map[string][]byte
+	if overlay != "" {
+		overlayFiles, err = readOverlay(overlay)
+		if err != nil {
+			fmt.Fprintf(cli.errStream, "Invalid arguments: %s\n", err)
+			return ExitCodeError
+		}
+	}
+
 	// opts are option struct for processGenerate()
 	opts := &generateOpts{
 		diffOpts: &diffOpts{
 			Mode:              Strict,
 			IncludeUnexported: includeUnexported,
 		},
-		diff:    diff,
-		write:   write,
-		list:    list,
-		reverse: reverse,
+		diff: diff,
+		// Selected tests (and the ones of -generate) are written
+		// unless they are listed, diffed or checked.
+		write:    write || ((interactive || generate) && !diff && !list && !jsonOut && !check),
+		list:     list,
+		reverse:  reverse,
+		order:    testOrder,
+		examples: examples,
+		bench:    bench,
+		fuzz:     fuzz,
+
+		table:  table,
+		mocks:  mocks,
+		assert: assertStyle,
+
+		parallel: parallel,
+		golden:   golden,
+		doc:      docComment,
+
+		orphans: orphans,
+		prune:   prune,
+		renames: renames,
+
+		json:    jsonOut,
+		line:    line,
+		overlay: overlayFiles,
+
+		interactive: interactive,
+		check:       check,
+		generate:    generate,
+	}
+	opts.impliesTable()
+
+	if summary {
+		opts.summary = newSummaryReport(opts.diffOpts.Mode)
+	}
+
+	switch reportFormat {
+	case "":
+	case "html", "json":
+		opts.report = newReport(opts.diffOpts.Mode, link)
+	default:
+		fmt.Fprintf(cli.errStream, "Invalid arguments: unknown report format: %s\n", reportFormat)
+		return ExitCodeError
+	}
+
+	if coverprofile != "" {
+		profile, err := readCoverProfile(coverprofile)
+		if err != nil {
+			cli.reportError(coverprofile, err)
+			return ExitCodeError
+		}
+		opts.coverage = newCoverReport(coverprofile, profile, threshold)
+	}
+
+	if since != "" || staged {
+		opts.changes, err = readGitChanges(since, staged, changedFuncs)
+		if err != nil {
+			cli.reportError(".", fmt.Errorf("failed to read changes: %w", err))
+			return ExitCodeError
+		}
+		opts.changedFuncs = changedFuncs
+	} else if changedFuncs {
+		fmt.Fprintf(cli.errStream, "Invalid arguments: -changed-funcs requires -since or -staged\n")
+		return ExitCodeError
+	}
+
+	if lsp {
+		return newLSPServer(cli.inStream, cli.outStream, opts).serve()
 	}
 
 	// By default, statusCode is ExitCodeOK and Run() returns it.
@@ -118,7 +327,7 @@ func (cli *CLI) Run(args []string) int {
 		switch fi, err := os.Stat(path); {
 		case err != nil:
 			// Output the error and proceeds next (but Change status code).
-			fmt.Fprintf(cli.errStream, "Failed to get file info: %s", err)
+			cli.reportError(path, err)
 			exitCode = ExitCodeError
 
 		case fi.IsDir():
@@ -161,7 +370,7 @@ func (cli *CLI) Run(args []string) int {
 
 			// Start walking.
 			if err := filepath.Walk(path, walkFn); err != nil {
-				fmt.Fprintf(cli.errStream, "Failed to walk: %s\n", err)
+				cli.reportError(path, fmt.Errorf("failed to walk: %w", err))
 				exitCode = ExitCodeError
 			}
 		default:
@@ -172,6 +381,35 @@ func (cli *CLI) Run(args []string) int {
 		}
 	}
 
+	if opts.summary != nil {
+		write := opts.summary.writeTable
+		if jsonOut {
+			write = opts.summary.writeJSON
+		}
+		if err := write(cli.outStream); err != nil {
+			fmt.Fprintf(cli.errStream, "Failed to write summary: %s\n", err)
+			return ExitCodeError
+		}
+	}
+
+	if opts.coverage != nil {
+		opts.coverage.sort()
+		write := opts.coverage.writeTable
+		if jsonOut {
+			write = opts.coverage.writeJSON
+		}
+		if err := write(cli.outStream); err != nil {
+			fmt.Fprintf(cli.errStream, "Failed to write coverage: %s\n", err)
+			return ExitCodeError
+		}
+	}
+
+	if opts.report != nil {
+		if status := cli.writeReport(opts.report, reportFormat, output, baseline); status != ExitCodeOK {
+			return status
+		}
+	}
+
 	return exitCode
 }
 
@@ -183,6 +421,95 @@ type generateOpts struct {
 	list  bool
 
 	reverse bool
+
+	// order is the order to insert generated test functions.
+	order Order
+
+	// examples enables generating example functions.
+	examples bool
+
+	// bench enables generating benchmark functions.
+	bench bool
+
+	// fuzz enables generating fuzz tests.
+	fuzz bool
+
+	// table enables generating table-driven test body. It's implied
+	// by the options of table-driven tests (see impliesTable).
+	table bool
+
+	// mocks enables generating fakes of interfaces.
+	mocks bool
+
+	// assert is the style of comparisons in table-driven tests.
+	assert AssertStyle
+
+	// parallel makes generated tests run in parallel.
+	parallel bool
+
+	// golden enables comparing results with golden files.
+	golden bool
+
+	// doc enables using doc comments of targets in tests.
+	doc bool
+
+	// orphans reports orphaned tests instead of generating tests.
+	orphans bool
+
+	// prune deletes empty orphaned tests and reports the others.
+	prune bool
+
+	// renames are renamed targets whose tests are renamed.
+	renames []*Rename
+
+	// only restricts targets to the functions and methods
+	// (e.g., `Add` or `User.Add`) if it's not empty.
+	only []string
+
+	// line restricts targets to the function or method declared
+	// at the line of the source file if it's not zero.
+	line int
+
+	// json prints the changes of files as JSON (see jsonResult)
+	// instead of the content.
+	json bool
+
+	// overlay is the content of files keyed by their absolute paths
+	// which is used instead of the files (e.g., unsaved editor buffers).
+	overlay map[string][]byte
+
+	// interactive lets user select the tests to generate
+	// (see selectTargets).
+	interactive bool
+
+	// summary collects the counts of functions, methods and their tests
+	// instead of generating tests if it's not nil (see -summary).
+	summary *summaryReport
+
+	// report collects the statuses of functions, methods and orphaned
+	// tests instead of generating tests if it's not nil (see -report).
+	report *report
+
+	// coverage collects the statement coverage of functions and methods
+	// in the coverage profile instead of generating tests if it's not
+	// nil (see -coverprofile).
+	coverage *coverReport
+
+	// changes restricts source files to the changed ones in git
+	// if it's not nil (see -since and -staged).
+	changes gitChanges
+
+	// changedFuncs restricts targets to the functions and methods
+	// whose lines are changed in changes.
+	changedFuncs bool
+
+	// check reports the functions and methods without tests instead
+	// of generating tests, and fails if there is any (see -check).
+	check bool
+
+	// generate restricts source files to the ones with the generate
+	// directive, whose options are applied (see -generate).
+	generate bool
 }
 
 func (cli *CLI) processGenerate(srcPath string, opts *generateOpts) int {
@@ -192,68 +519,316 @@ func (cli *CLI) processGenerate(srcPath string, opts *generateOpts) int {
 		testPath = srcPath
 		srcPath, err = SrcFilePath(testPath)
 		if err != nil {
-			fmt.Fprintf(cli.errStream, "Failed to get src file path: %s\n", err)
+			cli.reportError(testPath, fmt.Errorf("failed to get source file path: %w", err))
 			return ExitCodeError
 		}
 	} else {
 		var err error
 		testPath, err = TestFilePath(srcPath)
 		if err != nil {
-			fmt.Fprintf(cli.errStream, "Failed to get go test file path: %s\n", err)
+			cli.reportError(srcPath, fmt.Errorf("failed to get test file path: %w", err))
 			return ExitCodeError
 		}
 	}
 
+	if opts.changes != nil {
+		if _, ok := opts.changes.lines(srcPath); !ok {
+			Debugf("Skip unchanged %q", srcPath)
+			return ExitCodeOK
+		}
+	}
+
+	if opts.generate {
+		merged, ok, err := directiveOpts(srcPath, opts)
+		if err != nil {
+			cli.reportError(srcPath, err)
+			return ExitCodeError
+		}
+		if !ok {
+			return ExitCodeOK
+		}
+		opts = merged
+	}
+
+	if opts.check {
+		return cli.processCheck(srcPath, testPath, opts)
+	}
+
+	if opts.orphans {
+		return cli.processOrphans(srcPath, testPath, opts)
+	}
+
+	if opts.summary != nil {
+		return cli.processSummary(srcPath, testPath, opts)
+	}
+
+	if opts.report != nil {
+		return cli.processReport(srcPath, testPath, opts)
+	}
+
+	if opts.coverage != nil {
+		return cli.processCoverage(srcPath, testPath, opts)
+	}
+
+	if opts.interactive {
+		candidates, err := missingTargets(srcPath, testPath, opts)
+		if err != nil {
+			cli.reportError(srcPath, err)
+			return ExitCodeError
+		}
+		if len(candidates) == 0 {
+			return ExitCodeOK
+		}
+
+		only, err := cli.selectTargets(srcPath, candidates)
+		if err != nil {
+			cli.reportError(srcPath, err)
+			return ExitCodeError
+		}
+		if len(only) == 0 {
+			return ExitCodeOK
+		}
+
+		selected := *opts
+		selected.only = only
+		opts = &selected
+	}
+
+	if opts.json {
+		return cli.processJSON(srcPath, testPath, opts)
+	}
+
+	if opts.prune {
+		if status := cli.reportKeptOrphans(srcPath, testPath, opts); status != ExitCodeOK {
+			return status
+		}
+	}
+
 	// Run actual gotests to path
-	goTestFile, err := goTestGenerate(srcPath, testPath, opts)
+	outFiles, err := goTestGenerate(srcPath, testPath, opts)
+	if err != nil {
+		cli.reportError(srcPath, err)
+		return ExitCodeError
+	}
+
+	// The first one is the test file and the rest are supporting files
+	// (e.g., fakes for -mocks) which are output only when changed.
+	for i, outFile := range outFiles {
+		if status := cli.processOutput(outFile, i == 0, opts); status != ExitCodeOK {
+			return status
+		}
+	}
+
+	return ExitCodeOK
+}
+
+// writeReport writes the report in format to the file of output (or
+// outStream if it's empty). It's compared with the JSON report of
+// baseline if it's not empty.
+func (cli *CLI) writeReport(r *report, format, output, baseline string) int {
+	if baseline != "" {
+		if err := r.readBaseline(baseline); err != nil {
+			cli.reportError(baseline, err)
+			return ExitCodeError
+		}
+	}
+
+	w := cli.outStream
+	if output != "" {
+		f, err := os.Create(output)
+		if err != nil {
+			cli.reportError(output, err)
+			return ExitCodeError
+		}
+		defer f.Close()
+		w = f
+	}
+
+	if err := r.write(w, format); err != nil {
+		cli.reportError(output, fmt.Errorf("failed to write report: %w", err))
+		return ExitCodeError
+	}
+	return ExitCodeOK
+}
+
+// reportError writes err of processing the file of path to errStream
+// in the form of `path:line:col: message` (see positionedErrors) which
+// editors can jump to. Paths are relative to the current directory.
+func (cli *CLI) reportError(path string, err error) {
+	for _, e := range positionedErrors(path, err) {
+		pos := e.Pos
+		if rel, err := fmtPath(pos.Filename); err == nil {
+			pos.Filename = rel
+		}
+		fmt.Fprintf(cli.errStream, "%s: %s\n", pos, e.Msg)
+	}
+}
+
+// processJSON prints the changes of the test file (and the supporting
+// files) as jsonResult. Files are not written.
+func (cli *CLI) processJSON(srcPath, testPath string, opts *generateOpts) int {
+	path, err := filepath.Abs(srcPath)
+	if err != nil {
+		cli.reportError(srcPath, err)
+		return ExitCodeError
+	}
+
+	exitCode := ExitCodeOK
+	res := &jsonResult{Source: path, Files: []*jsonFile{}}
+
+	outFiles, err := goTestGenerate(srcPath, testPath, opts)
 	if err != nil {
-		fmt.Fprintf(cli.errStream, "Failed to generate: %s\n", err)
+		res.Errors = newJSONErrors(path, err)
+		exitCode = ExitCodeError
+	}
+
+	for _, outFile := range outFiles {
+		f, err := newJSONFile(outFile, opts.diff)
+		if err != nil {
+			res.Errors = newJSONErrors(path, err)
+			exitCode = ExitCodeError
+			break
+		}
+		if f != nil {
+			res.Files = append(res.Files, f)
+		}
+	}
+
+	if err := json.NewEncoder(cli.outStream).Encode(res); err != nil {
+		cli.reportError(srcPath, fmt.Errorf("failed to write result: %w", err))
 		return ExitCodeError
 	}
 
+	return exitCode
+}
+
+// processOrphans reports orphaned tests in the test file.
+func (cli *CLI) processOrphans(srcPath, testPath string, opts *generateOpts) int {
+	goTestFile, orphans, status := cli.testFileOrphans(srcPath, testPath, opts)
+	if goTestFile == nil {
+		return status
+	}
+
+	path, err := fmtPath(testPath)
+	if err != nil {
+		cli.reportError(testPath, err)
+		return ExitCodeError
+	}
+
+	for _, orphan := range orphans {
+		line := goTestFile.FSet.Position(orphan.Decl.Pos()).Line
+		fmt.Fprintf(cli.outStream, "%s:%d: %s: %s does not exist\n", path, line, orphan.Name(), orphan.Target)
+	}
+
+	return ExitCodeOK
+}
+
+// reportKeptOrphans reports the orphaned tests which -prune keeps since
+// they are not empty. They are reported without lines since the lines
+// change by pruning.
+func (cli *CLI) reportKeptOrphans(srcPath, testPath string, opts *generateOpts) int {
+	goTestFile, orphans, status := cli.testFileOrphans(srcPath, testPath, opts)
+	if goTestFile == nil {
+		return status
+	}
+
+	for _, orphan := range orphans {
+		if !isEmptyTest(orphan.Decl) {
+			cli.reportError(testPath, fmt.Errorf("%s: %s does not exist (kept since it's not empty)", orphan.Name(), orphan.Target))
+		}
+	}
+
+	return ExitCodeOK
+}
+
+// testFileOrphans returns the test file and its orphaned tests. It returns
+// nil test file (with the exit code) if the file does not exist or the
+// orphans can't be found.
+func (cli *CLI) testFileOrphans(srcPath, testPath string, opts *generateOpts) (*GoFile, []*Orphan, int) {
+	if _, err := os.Stat(testPath); os.IsNotExist(err) {
+		return nil, nil, ExitCodeOK
+	}
+
+	goTestFile, err := ParseFile(testPath)
+	if err != nil {
+		cli.reportError(testPath, fmt.Errorf("failed to parse go test file: %w", err))
+		return nil, nil, ExitCodeError
+	}
+
+	orphans, err := packageOrphans(srcPath, goTestFile, opts)
+	if err != nil {
+		cli.reportError(srcPath, fmt.Errorf("failed to find orphans: %w", err))
+		return nil, nil, ExitCodeError
+	}
+
+	return goTestFile, orphans, ExitCodeOK
+}
+
+// packageOrphans returns orphaned tests in goTestFile. Targets are
+// looked up in all source files of the package of srcPath.
+func packageOrphans(srcPath string, goTestFile *GoFile, opts *generateOpts) ([]*Orphan, error) {
+	pkgFiles, err := parsePackageFiles(filepath.Dir(srcPath))
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse package files: %w", err)
+	}
+
+	return findOrphans(goTestFile, pkgFiles, opts.diffOpts)
+}
+
+// processOutput handles the generated file (list/diff/write or print it).
+// always indicates the file is printed even if there is no change.
+func (cli *CLI) processOutput(outFile outputFile, always bool, opts *generateOpts) int {
 	// Genreate results as a []byte
-	resBytes, err := goTestFile.Generate()
+	resBytes, err := outFile.Generate()
 	if err != nil {
-		fmt.Fprintf(cli.errStream, "Failed to generate result from ast: %s\n", err)
+		cli.reportError(outFile.Path(), fmt.Errorf("failed to generate result: %w", err))
 		return ExitCodeError
 	}
 
+	changed := outFile.Changed(resBytes)
+
 	// Handle diff/write only when there is diff between result and original code.
-	if !bytes.Equal(goTestFile.SrcBytes, resBytes) {
+	if changed {
 
 		if opts.list {
 
-			path, err := fmtPath(goTestFile.FileName)
+			path, err := fmtPath(outFile.Path())
 			if err != nil {
-				fmt.Fprintf(cli.errStream, "Failed to format path: %s\n", err)
+				cli.reportError(outFile.Path(), err)
 				return ExitCodeError
 			}
 			fmt.Fprintf(cli.outStream, "%s\n", path)
 		}
 
 		if opts.diff {
-			data, err := doDiff(goTestFile.SrcBytes, resBytes)
+			data, err := doDiff(outFile.Original(), resBytes)
 			if err != nil {
-				fmt.Fprintf(cli.errStream, "Failed to compute diff: %s\n", err)
+				cli.reportError(outFile.Path(), fmt.Errorf("failed to compute diff: %w", err))
 				return ExitCodeError
 			}
-			fmt.Fprintf(cli.outStream, "diff %s\n", goTestFile.FileName)
+			fmt.Fprintf(cli.outStream, "diff %s\n", outFile.Path())
 			fmt.Fprintf(cli.outStream, "%s\n", data)
 		}
 
 		if opts.write {
-			err = ioutil.WriteFile(testPath, resBytes, 0644)
+			err := os.MkdirAll(filepath.Dir(outFile.Path()), 0755)
 			if err != nil {
-				fmt.Fprintf(cli.errStream, "Failed to write resutl to file: %s\n", err)
+				cli.reportError(outFile.Path(), err)
+				return ExitCodeError
+			}
+
+			err = ioutil.WriteFile(outFile.Path(), resBytes, 0644)
+			if err != nil {
+				cli.reportError(outFile.Path(), err)
 				return ExitCodeError
 			}
 		}
 	}
 
-	if !opts.list && !opts.diff && !opts.write {
+	if !opts.list && !opts.diff && !opts.write && (always || changed) {
 		_, err := cli.outStream.Write(resBytes)
 		if err != nil {
-			fmt.Fprintf(cli.errStream, "Failed to write resutl: %s\n", err)
+			cli.reportError(outFile.Path(), fmt.Errorf("failed to write result: %w", err))
 			return ExitCodeError
 		}
 	}
@@ -261,54 +836,190 @@ func (cli *CLI) processGenerate(srcPath string, opts *generateOpts) int {
 	return ExitCodeOK
 }
 
-func goTestGenerate(srcPath, testPath string, opts *generateOpts) (*GoFile, error) {
-	goFile, err := ParseFile(srcPath)
+func goTestGenerate(srcPath, testPath string, opts *generateOpts) ([]outputFile, error) {
+	goFile, err := parseFileOverlay(srcPath, opts.overlay)
 	if err != nil {
-		return nil, fmt.Errorf("failed to parse go file: %s", err)
+		return nil, fmt.Errorf("failed to parse go file: %w", err)
+	}
+	if err := goFile.resolveTypeParams(); err != nil {
+		return nil, fmt.Errorf("failed to parse package files: %w", err)
 	}
 	Debugf("%#v", goFile)
 
-	var goTestFile *GoFile
-	if _, err := os.Stat(testPath); os.IsNotExist(err) {
-		// If test file is not exist, create new one with the same pacakge
-		// declare with the source.
-		var err error
-		goTestFile, err = NewGoFile(testPath, goFile.PackageName)
+	only := opts.only
+	if opts.line > 0 {
+		name := goFile.targetAtLine(opts.line)
+		if name == "" {
+			return nil, &posError{
+				Pos: token.Position{Filename: goFile.FileName, Line: opts.line, Column: 1},
+				Msg: "no function or method is declared at this line",
+			}
+		}
+		only = []string{name}
+	}
+
+	if len(only) > 0 {
+		goFile.filterTargets(only)
+	}
+
+	opts.filterChanged(srcPath, goFile)
+
+	goTestFile, err := openTestFile(testPath, goFile.PackageName, opts.overlay)
+	if err != nil {
+		return nil, err
+	}
+	Debugf("goTestFile: %#v", goTestFile)
+
+	if len(opts.renames) > 0 {
+		if err := goTestFile.renameTests(opts.renames, opts.diffOpts); err != nil {
+			return nil, fmt.Errorf("failed to rename tests: %w", err)
+		}
+	}
+
+	if opts.prune {
+		orphans, err := packageOrphans(srcPath, goTestFile, opts)
 		if err != nil {
-			return nil, fmt.Errorf("failed to create new test file: %s", err)
+			return nil, fmt.Errorf("failed to find orphans: %w", err)
 		}
-	} else {
-		// If test file is exist, just parse it.
-		var err error
-		goTestFile, err = ParseFile(testPath)
+		goTestFile.pruneOrphans(orphans)
+	}
+
+	// fakes are fake type names of interfaces used in table-driven tests.
+	var mockFiles []*GoFile
+	fakes := make(map[string]string)
+	if opts.mocks {
+		mockFiles, fakes, err = addFakes(goFile, goTestFile.PackageName)
 		if err != nil {
-			return nil, fmt.Errorf("failed to parse go test file: %s", err)
+			return nil, fmt.Errorf("failed to add fakes: %w", err)
+		}
+	}
+
+	// External test package (e.g., `p_test`) uses the identifiers of the
+	// package qualified. They are qualified after adding fakes, which
+	// looks up interfaces by their names in source.
+	if isExternalTest(goTestFile.PackageName) {
+		pkgFiles, err := parsePackageFiles(filepath.Dir(srcPath))
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse package files: %w", err)
+		}
+		goFile.qualify(qualifiedNames(goFile.PackageName, append(pkgFiles, goFile)))
+		if importPath := packageImportPath(srcPath); importPath != "" {
+			goTestFile.addImport("", importPath)
 		}
 	}
-	Debugf("goTestFile: %#v", goTestFile)
+
+	goVersion := moduleGoVersion(filepath.Dir(srcPath))
+
+	builder := &testBuilder{
+		table:    opts.table,
+		style:    opts.assert,
+		fakes:    fakes,
+		parallel: opts.parallel,
+		golden:   opts.golden,
+		doc:      opts.doc,
+
+		// Each iteration has its own loop variable since Go 1.22.
+		captureLoopVar: !goVersionAtLeast(goVersion, "1.22"),
+
+		// testing.T.Context is added in Go 1.24.
+		testContext: goVersionAtLeast(goVersion, "1.24"),
+	}
+	if opts.table {
+		for _, imp := range assertImports[opts.assert] {
+			goTestFile.addImport(imp[0], imp[1])
+		}
+	}
+
+	srcTests, err := goFile.expectTestFuncs(opts.diffOpts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get expected test funcs: %w", err)
+	}
+	p := newPlacer(opts.order, goTestFile, srcTests)
 
 	diffFuncs, err := goFile.diffFuncs(goTestFile, opts.diffOpts)
 	if err != nil {
-		return nil, fmt.Errorf("failed to diff source file and test file: %s", err)
+		return nil, fmt.Errorf("failed to diff source file and test file: %w", err)
 	}
 	Debugf("Diff Funcs: %#v", diffFuncs)
 
 	funcTmpl := defaultExpectTestFuncTmpl
-	if err := goTestFile.addFuncTestFuncs(diffFuncs, funcTmpl); err != nil {
-		return nil, fmt.Errorf("failed to add func test funcs: %s", err)
+	if err := goTestFile.addFuncTestFuncs(diffFuncs, funcTmpl, builder.build, p); err != nil {
+		return nil, fmt.Errorf("failed to add func test funcs: %w", err)
 	}
 
 	diffMethods, err := goFile.diffMethods(goTestFile, opts.diffOpts)
 	if err != nil {
-		return nil, fmt.Errorf("failed to diff source file and test file: %s", err)
+		return nil, fmt.Errorf("failed to diff source file and test file: %w", err)
 	}
 	Debugf("Diff Methods: %#v", diffMethods)
 
 	funcTmpl = defaultExpectTestFuncMethodTmpl
-	if err := goTestFile.addMethodTestFuncs(diffMethods, funcTmpl); err != nil {
-		return nil, fmt.Errorf("failed to add method test funcs: %s", err)
+	if err := goTestFile.addMethodTestFuncs(diffMethods, funcTmpl, builder.build, p); err != nil {
+		return nil, fmt.Errorf("failed to add method test funcs: %w", err)
+	}
+
+	if len(builder.goldenPaths) > 0 {
+		testFiles, err := parseTestFiles(filepath.Dir(testPath))
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse test files: %w", err)
+		}
+
+		if !hasVar(append(testFiles, goTestFile), "update") {
+			goTestFile.appendDecl([]byte(updateFlagDecl))
+		}
+	}
+
+	p.place()
+
+	if opts.fuzz && goVersion != "" && !goVersionAtLeast(goVersion, "1.18") {
+		return nil, fmt.Errorf("fuzz test requires Go 1.18 or later (go.mod declares %s)", goVersion)
 	}
 
+	for _, kind := range opts.funcKinds(goVersion) {
+		if err := addKindFuncs(goFile, goTestFile, testPath, kind, opts); err != nil {
+			return nil, fmt.Errorf("failed to add %s funcs: %w", strings.ToLower(kind.name), err)
+		}
+	}
+
+	outFiles := []outputFile{goTestFile}
+	for _, mockFile := range mockFiles {
+		outFiles = append(outFiles, mockFile)
+	}
+
+	// Golden files are created only when writing the test file.
+	if opts.write {
+		outFiles = append(outFiles, goldenFiles(filepath.Dir(testPath), builder.goldenPaths)...)
+	}
+
+	return outFiles, nil
+}
+
+// openTestFile parses the test file of path (or its content in overlay).
+// If it does not exist, it returns new one which declares pkgName package.
+func openTestFile(path, pkgName string, overlay map[string][]byte) (*GoFile, error) {
+	if abs, err := filepath.Abs(path); err == nil && overlay[abs] != nil {
+		goTestFile, err := parseFileOverlay(path, overlay)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse go test file: %w", err)
+		}
+		return goTestFile, nil
+	}
+
+	if _, err := os.Stat(path); os.IsNotExist(err) {
+		// If test file is not exist, create new one with the same pacakge
+		// declare with the source.
+		goTestFile, err := NewGoFile(path, pkgName)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create new test file: %s", err)
+		}
+		return goTestFile, nil
+	}
+
+	// If test file is exist, just parse it.
+	goTestFile, err := ParseFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse go test file: %w", err)
+	}
 	return goTestFile, nil
 }
 
@@ -415,6 +1126,27 @@ https://github.com/tcnksm/gotests
 Usage:
 
   gotests [options] PATH ...
+  gotests lsp [options]
+  gotests hook install [options] [PATH ...]
+
+  Errors are reported as 'path:line:col: message' (or 'path: message'
+  if there is no position in the file).
+
+Commands:
+
+  lsp            Run language server over stdio which offers code actions
+                 'Generate test for X' (for the function/method at the
+                 cursor) and 'Generate missing tests in file' on source
+                 files. Their edits target the test file. Options are
+                 applied to the generated tests.
+
+  hook install   Install git pre-commit hook (.git/hooks/pre-commit) which
+                 runs 'gotests -check -staged [options] [PATH ...]' (PATH
+                 is '.' by default) and rejects commits of functions and
+                 methods without tests. Existing hooks are not overwritten
+                 unless they are installed by gotests. For example,
+                 'gotests hook install -changed-funcs' checks only the
+                 changed functions/methods.
 
 Options:
 
@@ -428,6 +1160,168 @@ Options:
 
   -i             Include unexport function/method for generating target.
 
+  -order=ORDER   Order to insert generated tests into existing test file.
+                 'append' (default) adds them to the end of the file,
+                 'source' inserts them next to the tests of neighboring
+                 source declarations and 'alpha' keeps tests sorted by name.
+
+  -table         Generate table-driven test body which calls the function
+                 with args of each test case and compares the results,
+                 instead of an empty test function. context.Context of
+                 the first param is created by context.Background() (or
+                 t.Context() if go.mod declares Go 1.24 or later) and
+                 a canceled context case is added (commented out with
+                 TODO) if it returns error.
+
+  -mocks         Generate fakes (structs with function fields) of interfaces
+                 declared in the source file or used as params into
+                 'mock_<file>_test.go' (<file> is the file declaring the
+                 interface), and use them in table-driven test args.
+                 Only interfaces declared in the package are faked: params
+                 of other packages' interfaces (e.g. io.Reader) get none,
+                 and methods of such embedded interfaces are not
+                 implemented (calling them panics). This implies -table.
+
+  -assert=STYLE  Style of comparisons in table-driven tests. 'std' (default)
+                 uses reflect.DeepEqual and t.Errorf, 'testify' uses
+                 assert.Equal and require.NoError, 'go-cmp' uses cmp.Diff
+                 and 'quicktest' uses qt.DeepEquals. This implies -table.
+
+  -parallel      Call t.Parallel() in generated tests and in each subtest
+                 of table-driven tests. The loop variable is captured
+                 ('tt := tt') unless go.mod declares Go 1.22 or later.
+
+  -golden        Compare the result of functions which return []byte, string
+                 or io.Reader with 'testdata/<TestName>/<case>.golden'.
+                 'go test -update' rewrites golden files with the results.
+                 With -w, empty golden files are also created. This implies
+                 -table.
+
+  -doc           Add the first sentence of the doc comment of the function
+                 above the generated test. With -table, test cases are
+                 also seeded from 'Example:' lines and bullet lines
+                 ('- ', '* ' or '+ ') of the doc comment.
+
+  -orphans       Report tests whose target functions/methods no longer exist
+                 in the package instead of generating tests. Test names
+                 are mapped back to targets by the naming rule (e.g.,
+                 'TestUser_Add' to 'User.Add'), ignoring the case of the
+                 first letters. Tests of variations (e.g.,
+                 'TestUser_Add_error') and tests which are not mapped back
+                 to exactly one target are not reported.
+
+  -prune         Delete empty orphaned tests. Orphaned tests with bodies
+                 are kept and reported.
+
+  -rename=OLD=NEW[,OLD=NEW...]
+                 Rename tests (and benchmarks, examples and fuzz tests) of
+                 renamed functions/methods (e.g., 'User.Add=User.Insert')
+                 in place, together with the calls of the target and its
+                 names in string literals (e.g., t.Run names) in them.
+
+  -json          Print the changes of files as JSON (one object per source
+                 file) instead of the content, for editor integration.
+                 Files are not written. Each change has edits (byte offset,
+                 line and column of start and end, and the new text) to
+                 apply from the last one, the added tests with their lines
+                 and, with -d, the diff. Syntax errors have positions:
+                 {"source": PATH, "files": [{"path": PATH, "create": BOOL,
+                 "edits": [...], "tests": [...], "diff": DIFF}],
+                 "errors": [{"path": PATH, "line": N, "col": N,
+                 "message": MSG}]}
+
+  -line=N        Generate only the test of the function/method declared
+                 at line N of the source file (e.g., the line at cursor).
+
+  -overlay=FILE  Read files (e.g., unsaved editor buffers) from the
+                 replacement files in FILE instead of their paths. The
+                 format is the same as 'go build -overlay':
+                 {"Replace": {PATH: REPLACEMENT_PATH}}
+
+  -interactive   List the functions/methods without tests with their
+                 signatures and let you toggle which tests to generate
+                 before generating them. The selected tests are written
+                 (as with -w) unless -d, -l or -json is given. If stdin is
+                 not a terminal, a line of the numbers to generate
+                 (e.g., '1 3 5-7') is read from it instead of prompting,
+                 and nothing is generated if it's empty.
+
+  -summary       Print the counts of exported/unexported functions and
+                 methods, how many of them have tests, how many tests would
+                 be generated and the percentage of the tested ones (test
+                 presence coverage) per package and overall, instead of
+                 generating tests. With -json, it's printed as JSON.
+
+  -report=FORMAT Write the report of functions/methods of each package and
+                 file with their statuses (tested, missing or orphaned) and
+                 the percentage of the tested ones, instead of generating
+                 tests. FORMAT is 'html' (browsable static page) or 'json'.
+
+  -o=FILE        Write the report to FILE instead of stdout.
+
+  -baseline=FILE Compare the coverage of the report with the JSON report
+                 saved in FILE (e.g., last week's one) to show the trend.
+
+  -link=URL      Link of functions in the HTML report. '{path}' (relative
+                 to the current directory) and '{line}' are replaced
+                 (default: '{path}#L{line}'). For example,
+                 'https://github.com/user/repo/blob/main/{path}#L{line}'.
+
+  -coverprofile=FILE
+                 Print the statement coverage of each function/method in
+                 the coverage profile FILE ('go test -coverprofile=FILE')
+                 with its test, instead of generating tests. Functions are
+                 sorted by priority to generate tests: 'uncovered' ones
+                 (no statement covered) first and then 'low' ones (below
+                 -threshold), and the ones without tests before the ones
+                 with tests. With -json, it's printed as JSON.
+
+  -threshold=N   Coverage percentage below which functions are flagged
+                 'low' with -coverprofile (default: 50).
+
+  -since=REF     Process only the source files changed since git REF
+                 (e.g., 'main' or 'HEAD~3') in the working tree, as listed
+                 by 'git diff --name-only REF'. PATHs are still walked but
+                 the other files are skipped.
+
+  -staged        Process only the source files whose changes are staged
+                 ('git diff --cached --name-only'), e.g., in pre-commit
+                 hooks. With -since, they are compared with REF instead
+                 of HEAD.
+
+  -changed-funcs With -since or -staged, generate (or check) only the tests
+                 of the functions/methods whose lines (or doc comments)
+                 changed.
+
+  -check         Print the functions/methods without tests as
+                 'path:line: Target: TestName is missing' instead of
+                 generating tests, and exit with non-zero status if there
+                 is any (e.g., in pre-commit hooks or CI).
+
+  -generate      Generate tests only of the source files which have the
+                 '//gotests:generate [options]' directive, with the
+                 options of the command line and the directive (e.g.,
+                 '//gotests:generate -table -parallel'). Directive options
+                 must be in the form of -name or -name=value and only the
+                 options of generated tests (-i, -order, -examples, -bench,
+                 -fuzz, -table, -mocks, -assert, -parallel, -golden and
+                 -doc) are allowed. Tests are
+                 written (as with -w) unless -d, -l, -check or -json is
+                 given. Without PATHs, the directory of $GOFILE is used,
+                 so that 'go generate' keeps tests scaffolded with
+                 '//go:generate gotests -generate' in the package.
+
+  -examples      Also generate example functions (ExampleX, ExampleT_M)
+                 for exported functions/methods which don't have one in
+                 any test file of the package.
+
+  -bench         Also generate benchmark functions (BenchmarkX, BenchmarkT_M)
+                 which call the function with zero value args in the loop.
+                 b.Loop is used when go.mod declares Go 1.24 or later.
+
+  -fuzz          Also generate fuzz tests (FuzzX) for functions whose params
+                 are all fuzzable types (string, []byte, bool, ints and floats).
+
   -reverse, -r   (experimental) Allow to provide test file instead of source file.
                  By default, gotests expects source file PATH provided.
                  With this flag, the test file can be given. 
@@ -3,6 +3,9 @@ package main
 import (
 	"bytes"
 	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
 	"strings"
 	"testing"
 )
@@ -22,3 +25,39 @@ func TestCLI_Run(t *testing.T) {
 		t.Errorf("expected %q to eq %q", errStream.String(), expected)
 	}
 }
+
+func TestCLI_reportKeptOrphans(t *testing.T) {
+	dir, err := ioutil.TempDir("", Name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	src := "package p\n\nfunc Add() {}\n"
+	testSrc := "package p\n\nimport \"testing\"\n\nfunc TestSub(t *testing.T) {}\n\nfunc TestMul(t *testing.T) {\n\tt.Log()\n}\n"
+	srcPath := filepath.Join(dir, "p.go")
+	testPath := filepath.Join(dir, "p_test.go")
+	if err := ioutil.WriteFile(srcPath, []byte(src), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(testPath, []byte(testSrc), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	outStream, errStream := new(bytes.Buffer), new(bytes.Buffer)
+	cli := &CLI{outStream: outStream, errStream: errStream}
+	if status := cli.reportKeptOrphans(srcPath, testPath, &generateOpts{diffOpts: &diffOpts{}}); status != ExitCodeOK {
+		t.Errorf("expected %d to eq %d", status, ExitCodeOK)
+	}
+
+	path, err := fmtPath(testPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Empty TestSub is deleted without being reported.
+	expected := path + ": TestMul: Mul does not exist (kept since it's not empty)\n"
+	if got := errStream.String(); got != expected {
+		t.Errorf("expected %q to eq %q", got, expected)
+	}
+}
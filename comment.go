@@ -0,0 +1,76 @@
+package main
+
+import (
+	"go/ast"
+	"strings"
+)
+
+// docComment is the doc comment of target used in generated test.
+type docComment struct {
+	// Synopsis is the first sentence of the doc comment.
+	Synopsis string
+
+	// Cases are test case names seeded from `Example:` lines and
+	// bullet lines (e.g., `- empty string returns error`).
+	Cases []string
+}
+
+// bulletPrefixes are the markers of list items in doc comment.
+var bulletPrefixes = []string{"- ", "* ", "+ "}
+
+// parseDocComment parses doc comment of function or method.
+// It returns nil if there is no doc comment.
+func parseDocComment(doc *ast.CommentGroup) *docComment {
+	if doc == nil {
+		return nil
+	}
+
+	text := doc.Text()
+	if strings.TrimSpace(text) == "" {
+		return nil
+	}
+
+	dc := &docComment{Synopsis: firstSentence(text)}
+	for _, line := range strings.Split(text, "\n") {
+		line = strings.TrimSpace(line)
+
+		if strings.HasPrefix(line, "Example:") {
+			if name := strings.TrimSpace(strings.TrimPrefix(line, "Example:")); name != "" {
+				dc.Cases = append(dc.Cases, name)
+			}
+			continue
+		}
+
+		for _, prefix := range bulletPrefixes {
+			if strings.HasPrefix(line, prefix) {
+				if name := strings.TrimSpace(strings.TrimPrefix(line, prefix)); name != "" {
+					dc.Cases = append(dc.Cases, name)
+				}
+				break
+			}
+		}
+	}
+
+	return dc
+}
+
+// firstSentence returns the first sentence of the first paragraph
+// of text joined into one line.
+func firstSentence(text string) string {
+	var lines []string
+	for _, line := range strings.Split(strings.TrimSpace(text), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" {
+			break
+		}
+		lines = append(lines, line)
+	}
+	para := strings.Join(lines, " ")
+
+	for i := 0; i < len(para); i++ {
+		if para[i] == '.' && (i+1 == len(para) || para[i+1] == ' ') {
+			return para[:i+1]
+		}
+	}
+	return para
+}
@@ -0,0 +1,59 @@
+package main
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseDocComment(t *testing.T) {
+	src := `package p
+
+// Parse parses s as an integer. It trims spaces
+// before parsing.
+//
+// Example: "42" returns 42
+//
+// Errors:
+//   - empty string returns error
+//   * non-digit returns error
+func Parse(s string) (int, error) { return 0, nil }
+
+func Noop() {}
+`
+	goFile, err := parse("p.go", strings.NewReader(src))
+	if err != nil {
+		t.Fatalf("parse failed: %s", err)
+	}
+
+	dc := parseDocComment(goFile.Funcs[0].Decl.Doc)
+	if dc.Synopsis != "Parse parses s as an integer." {
+		t.Errorf("expected %q to eq %q", dc.Synopsis, "Parse parses s as an integer.")
+	}
+
+	expected := []string{`"42" returns 42`, "empty string returns error", "non-digit returns error"}
+	if !reflect.DeepEqual(dc.Cases, expected) {
+		t.Errorf("expected %q to eq %q", dc.Cases, expected)
+	}
+
+	if dc := parseDocComment(goFile.Funcs[1].Decl.Doc); dc != nil {
+		t.Errorf("expected %#v to be nil", dc)
+	}
+}
+
+func TestFirstSentence(t *testing.T) {
+	cases := []struct {
+		text     string
+		expected string
+	}{
+		{"Add adds a and b.\n", "Add adds a and b."},
+		{"Add adds\na and b. It panics.\n", "Add adds a and b."},
+		{"Add adds v1.2 values\n\nSecond paragraph.\n", "Add adds v1.2 values"},
+	}
+
+	for _, tc := range cases {
+		if got := firstSentence(tc.text); got != tc.expected {
+			t.Errorf("expected %q to eq %q", got, tc.expected)
+		}
+	}
+}
@@ -0,0 +1,395 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"go/ast"
+	"go/token"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+)
+
+// Flags of coverEntry.
+const (
+	// coverUncovered is a function none of whose statements is covered.
+	coverUncovered = "uncovered"
+
+	// coverLow is a function whose coverage is below the threshold.
+	coverLow = "low"
+)
+
+// profileBlock is a block of a coverage profile
+// (`file:line.col,line.col numStmt count`).
+type profileBlock struct {
+	StartLine, StartCol int
+	EndLine, EndCol     int
+	NumStmt, Count      int
+}
+
+// coverProfile is the blocks of a coverage profile (the output of
+// `go test -coverprofile`) keyed by their file names. File names are
+// import paths of packages joined with file names
+// (e.g., `github.com/user/repo/pkg/file.go`).
+type coverProfile map[string][]profileBlock
+
+// readCoverProfile reads the coverage profile of path.
+func readCoverProfile(path string) (coverProfile, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	profile := coverProfile{}
+
+	// Blocks appear more than once if their package is covered by tests
+	// of other packages (-coverpkg). Their counts are summed up.
+	type key struct {
+		name                string
+		startLine, startCol int
+	}
+	index := map[key]int{}
+
+	scanner := bufio.NewScanner(f)
+	for n := 1; scanner.Scan(); n++ {
+		line := scanner.Text()
+		if line == "" || strings.HasPrefix(line, "mode:") {
+			continue
+		}
+
+		name, block, err := parseProfileLine(line)
+		if err != nil {
+			return nil, &posError{
+				Pos: token.Position{Filename: path, Line: n, Column: 1},
+				Msg: err.Error(),
+			}
+		}
+
+		k := key{name, block.StartLine, block.StartCol}
+		if i, ok := index[k]; ok {
+			profile[name][i].Count += block.Count
+			continue
+		}
+		index[k] = len(profile[name])
+		profile[name] = append(profile[name], block)
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return profile, nil
+}
+
+// parseProfileLine parses a line of coverage profile
+// (e.g., `github.com/user/repo/file.go:3.24,5.2 1 1`).
+func parseProfileLine(line string) (string, profileBlock, error) {
+	var b profileBlock
+	i := strings.LastIndex(line, ":")
+	if i < 0 {
+		return "", b, fmt.Errorf("invalid coverage profile line: %s", line)
+	}
+
+	name := line[:i]
+	_, err := fmt.Sscanf(line[i+1:], "%d.%d,%d.%d %d %d",
+		&b.StartLine, &b.StartCol, &b.EndLine, &b.EndCol, &b.NumStmt, &b.Count)
+	if err != nil {
+		return "", b, fmt.Errorf("invalid coverage profile line: %s", line)
+	}
+	return name, b, nil
+}
+
+// blocks returns the blocks of the file of path. The file is looked up
+// by its module path (see moduleFilePath). Otherwise (e.g., in GOPATH),
+// files in the profile are matched by their trailing path elements, and
+// the one which has the most matching elements is chosen. It returns
+// false if there is no such file or more than one.
+func (p coverProfile) blocks(path string) ([]profileBlock, bool) {
+	if name := moduleFilePath(path); name != "" {
+		blocks, ok := p[name]
+		return blocks, ok
+	}
+
+	elems := strings.Split(filepath.ToSlash(path), "/")
+
+	best, found := 0, ""
+	ambiguous := false
+	for name := range p {
+		names := strings.Split(name, "/")
+		n := 0
+		for n < len(elems) && n < len(names) && elems[len(elems)-1-n] == names[len(names)-1-n] {
+			n++
+		}
+
+		switch {
+		case n == 0 || n < best:
+		case n == best:
+			ambiguous = true
+		default:
+			best, found, ambiguous = n, name, false
+		}
+	}
+
+	if found == "" || ambiguous {
+		return nil, false
+	}
+	return p[found], true
+}
+
+// coverEntry is the coverage of a function or method.
+type coverEntry struct {
+	// Name is the name of the function or method (e.g., `User.Add`).
+	Name string `json:"name"`
+
+	// Test is the name of the (expected) test and Tested is true
+	// if it exists.
+	Test   string `json:"test"`
+	Tested bool   `json:"tested"`
+
+	// Path and Line are the declaration of the function or method.
+	Path string `json:"path"`
+	Line int    `json:"line"`
+
+	// Statements and Covered are the numbers of the statements of the
+	// function and the covered ones, and Coverage is the percentage.
+	Statements int     `json:"statements"`
+	Covered    int     `json:"covered"`
+	Coverage   float64 `json:"coverage"`
+
+	// Flag is coverUncovered, coverLow or empty.
+	Flag string `json:"flag,omitempty"`
+}
+
+// priority returns the priority of generating the test of the entry
+// (smaller is higher). Functions without coverage are first, tested ones
+// with low coverage (whose tests are not enough) are next, and the others
+// are last. Functions without tests are prior to the ones with tests.
+func (e *coverEntry) priority() int {
+	p := 4
+	switch e.Flag {
+	case coverUncovered:
+		p = 0
+	case coverLow:
+		p = 2
+	}
+	if e.Tested {
+		p++
+	}
+	return p
+}
+
+// coverReport is the coverage of functions and methods (see -coverprofile).
+type coverReport struct {
+	Profile   string        `json:"profile"`
+	Threshold float64       `json:"threshold"`
+	Functions []*coverEntry `json:"functions"`
+
+	profile coverProfile
+}
+
+func newCoverReport(path string, profile coverProfile, threshold float64) *coverReport {
+	return &coverReport{
+		Profile:   path,
+		Threshold: threshold,
+		Functions: []*coverEntry{},
+		profile:   profile,
+	}
+}
+
+// add adds entries and flags them.
+func (r *coverReport) add(entries []*coverEntry) {
+	for _, e := range entries {
+		switch {
+		case e.Statements == 0:
+		case e.Covered == 0:
+			e.Flag = coverUncovered
+		case e.Coverage < r.Threshold:
+			e.Flag = coverLow
+		}
+		r.Functions = append(r.Functions, e)
+	}
+}
+
+// sort sorts functions by their priorities, and larger ones (which have
+// more uncovered statements) first in the same priority.
+func (r *coverReport) sort() {
+	sort.SliceStable(r.Functions, func(i, j int) bool {
+		a, b := r.Functions[i], r.Functions[j]
+		if a.priority() != b.priority() {
+			return a.priority() < b.priority()
+		}
+		return a.Statements-a.Covered > b.Statements-b.Covered
+	})
+}
+
+// writeTable writes the report as a table.
+func (r *coverReport) writeTable(w io.Writer) error {
+	tw := tabwriter.NewWriter(w, 0, 8, 2, ' ', 0)
+	fmt.Fprintln(tw, "FUNCTION\tTEST\tSTATEMENTS\tCOVERAGE\tFLAG")
+
+	for _, e := range r.Functions {
+		test := e.Test
+		if !e.Tested {
+			test = "-"
+		}
+		fmt.Fprintf(tw, "%s:%d: %s\t%s\t%d/%d\t%.1f%%",
+			e.Path, e.Line, e.Name, test, e.Covered, e.Statements, e.Coverage)
+
+		// The last cell is not padded to avoid trailing spaces.
+		if e.Flag != "" {
+			fmt.Fprintf(tw, "\t%s", e.Flag)
+		}
+		fmt.Fprintln(tw)
+	}
+
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+
+	_, err := fmt.Fprintf(w, "\nFunctions are sorted by priority: uncovered ones, then ones whose coverage is below %s%%.\n",
+		strconv.FormatFloat(r.Threshold, 'f', -1, 64))
+	return err
+}
+
+// writeJSON writes the report as JSON.
+func (r *coverReport) writeJSON(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(r)
+}
+
+// coverEntries returns the coverage of the functions and methods of the
+// source file in profile, and whether the source file is in profile.
+func coverEntries(srcPath, testPath string, profile coverProfile, opts *generateOpts) ([]*coverEntry, bool, error) {
+	path, err := filepath.Abs(srcPath)
+	if err != nil {
+		return nil, false, err
+	}
+
+	blocks, ok := profile.blocks(path)
+	if !ok {
+		return nil, false, nil
+	}
+
+	goFile, err := parseFileOverlay(srcPath, opts.overlay)
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to parse go file: %w", err)
+	}
+
+	goTestFile, err := openTestFile(testPath, goFile.PackageName, opts.overlay)
+	if err != nil {
+		return nil, false, err
+	}
+
+	diffOpts := opts.diffOpts
+	diffOpts.init()
+
+	diffFuncs, err := goFile.diffFuncs(goTestFile, diffOpts)
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to diff source file and test file: %s", err)
+	}
+
+	diffMethods, err := goFile.diffMethods(goTestFile, diffOpts)
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to diff source file and test file: %s", err)
+	}
+
+	src, err := fmtPath(srcPath)
+	if err != nil {
+		return nil, false, err
+	}
+
+	var entries []*coverEntry
+	entry := func(name, test string, missing bool, decl *ast.FuncDecl) {
+		e := &coverEntry{
+			Name:   name,
+			Test:   test,
+			Tested: !missing,
+			Path:   src,
+			Line:   goFile.FSet.Position(decl.Pos()).Line,
+		}
+		e.Statements, e.Covered = funcCoverage(goFile.FSet, decl, blocks)
+
+		e.Coverage = 100
+		if e.Statements > 0 {
+			e.Coverage = float64(e.Covered) * 100 / float64(e.Statements)
+		}
+		entries = append(entries, e)
+	}
+
+	for _, fun := range goFile.Funcs {
+		if contains(diffOpts.IgnoreFuncs, fun.Name) ||
+			(!diffOpts.IncludeUnexported && isUnExported(fun.Name)) {
+			continue
+		}
+
+		test, err := execFuncTmpl(diffOpts.ExpectTestFuncTmpl, fun)
+		if err != nil {
+			return nil, false, err
+		}
+		entry(fun.Name, test, containsFunc(diffFuncs, fun), fun.Decl)
+	}
+
+	for _, method := range goFile.Methods {
+		if !diffOpts.IncludeUnexported && isUnExported(method.Name) {
+			continue
+		}
+
+		test, err := execFuncTmpl(diffOpts.ExpectTestFuncMethodTmpl, method)
+		if err != nil {
+			return nil, false, err
+		}
+		entry(method.RecvName+"."+method.Name, test, containsMethod(diffMethods, method), method.Decl)
+	}
+
+	return entries, true, nil
+}
+
+// funcCoverage returns the numbers of statements of decl and the covered
+// ones in blocks, as `go tool cover -func` does.
+func funcCoverage(fset *token.FileSet, decl *ast.FuncDecl, blocks []profileBlock) (int, int) {
+	start := fset.Position(decl.Pos())
+	end := fset.Position(decl.End())
+
+	var statements, covered int
+	for _, b := range blocks {
+		if b.StartLine < start.Line || (b.StartLine == start.Line && b.StartCol < start.Column) {
+			continue
+		}
+		if b.EndLine > end.Line || (b.EndLine == end.Line && b.EndCol > end.Column) {
+			continue
+		}
+
+		statements += b.NumStmt
+		if b.Count > 0 {
+			covered += b.NumStmt
+		}
+	}
+	return statements, covered
+}
+
+// processCoverage adds the coverage of the functions and methods of the
+// source file to opts.coverage.
+func (cli *CLI) processCoverage(srcPath, testPath string, opts *generateOpts) int {
+	entries, ok, err := coverEntries(srcPath, testPath, opts.coverage.profile, opts)
+	if err != nil {
+		cli.reportError(srcPath, err)
+		return ExitCodeError
+	}
+
+	// Files which are not in the profile (e.g., excluded by build
+	// constraints) are skipped rather than reported as uncovered.
+	if !ok {
+		cli.reportError(srcPath, errors.New("not in coverage profile"))
+		return ExitCodeOK
+	}
+
+	opts.coverage.add(entries)
+	return ExitCodeOK
+}
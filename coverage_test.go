@@ -0,0 +1,176 @@
+package main
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestReadCoverProfile(t *testing.T) {
+	dir, err := ioutil.TempDir("", Name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	profile := `mode: set
+example.com/p/p.go:3.24,5.2 1 1
+example.com/p/p.go:7.24,9.2 2 0
+example.com/p/p.go:7.24,9.2 2 1
+example.com/q/p.go:3.24,5.2 1 0
+`
+	path := filepath.Join(dir, "cover.out")
+	if err := ioutil.WriteFile(path, []byte(profile), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	p, err := readCoverProfile(path)
+	if err != nil {
+		t.Fatalf("readCoverProfile failed: %s", err)
+	}
+
+	expected := []profileBlock{
+		{StartLine: 3, StartCol: 24, EndLine: 5, EndCol: 2, NumStmt: 1, Count: 1},
+		{StartLine: 7, StartCol: 24, EndLine: 9, EndCol: 2, NumStmt: 2, Count: 1},
+	}
+	blocks, ok := p.blocks("/src/p/p.go")
+	if !ok || len(blocks) != 2 || blocks[0] != expected[0] || blocks[1] != expected[1] {
+		t.Errorf("expected %+v to eq %+v", blocks, expected)
+	}
+
+	if _, ok := p.blocks("/src/r/p.go"); ok {
+		t.Errorf("expected /src/r/p.go to be ambiguous")
+	}
+	if _, ok := p.blocks("/src/p/other.go"); ok {
+		t.Errorf("expected /src/p/other.go not to be in profile")
+	}
+
+	if err := ioutil.WriteFile(path, []byte("mode: set\nexample.com/p/p.go:3.24\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	_, err = readCoverProfile(path)
+	if err == nil || err.Error() != path+":2:1: invalid coverage profile line: example.com/p/p.go:3.24" {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
+
+func TestCoverReport(t *testing.T) {
+	dir, err := ioutil.TempDir("", Name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	src := `package p
+
+func Add(a, b int) int {
+	return a + b
+}
+
+func Sub(a, b int) int {
+	if a < b {
+		return 0
+	}
+	return a - b
+}
+
+func Mul(a, b int) int {
+	return a * b
+}
+
+func Noop() {}
+`
+	testSrc := `package p
+
+import "testing"
+
+func TestAdd(t *testing.T) {}
+
+func TestSub(t *testing.T) {}
+`
+	srcPath := filepath.Join(dir, "p.go")
+	testPath := filepath.Join(dir, "p_test.go")
+	if err := ioutil.WriteFile(srcPath, []byte(src), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(testPath, []byte(testSrc), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	name := "example.com/" + filepath.Base(dir) + "/p.go"
+	profile := coverProfile{name: {
+		{StartLine: 3, StartCol: 24, EndLine: 5, EndCol: 2, NumStmt: 1, Count: 1},
+		{StartLine: 7, StartCol: 24, EndLine: 8, EndCol: 11, NumStmt: 1, Count: 1},
+		{StartLine: 8, StartCol: 11, EndLine: 10, EndCol: 3, NumStmt: 1, Count: 0},
+		{StartLine: 11, StartCol: 2, EndLine: 11, EndCol: 14, NumStmt: 1, Count: 0},
+		{StartLine: 14, StartCol: 24, EndLine: 16, EndCol: 2, NumStmt: 1, Count: 0},
+		{StartLine: 18, StartCol: 14, EndLine: 18, EndCol: 15, NumStmt: 0, Count: 1},
+	}}
+
+	opts := &generateOpts{diffOpts: &diffOpts{}}
+	entries, ok, err := coverEntries(srcPath, testPath, profile, opts)
+	if err != nil || !ok {
+		t.Fatalf("coverEntries failed: %v, %v", ok, err)
+	}
+
+	r := newCoverReport("cover.out", profile, 50)
+	r.add(entries)
+	r.sort()
+
+	got := make([]string, 0, len(r.Functions))
+	for _, e := range r.Functions {
+		got = append(got, e.Name+" "+e.Flag)
+	}
+	expected := []string{"Mul uncovered", "Sub low", "Noop ", "Add "}
+	if len(got) != len(expected) {
+		t.Fatalf("expected %q to eq %q", got, expected)
+	}
+	for i := range expected {
+		if got[i] != expected[i] {
+			t.Errorf("expected %q to eq %q", got[i], expected[i])
+		}
+	}
+
+	var buf bytes.Buffer
+	r.Functions = r.Functions[:2]
+	for _, e := range r.Functions {
+		e.Path = "p.go"
+	}
+	if err := r.writeTable(&buf); err != nil {
+		t.Fatalf("writeTable failed: %s", err)
+	}
+
+	expectedTable := `FUNCTION      TEST     STATEMENTS  COVERAGE  FLAG
+p.go:14: Mul  -        0/1         0.0%      uncovered
+p.go:7: Sub   TestSub  1/3         33.3%     low
+
+Functions are sorted by priority: uncovered ones, then ones whose coverage is below 50%.
+`
+	if got := buf.String(); got != expectedTable {
+		t.Errorf("expected %q to eq %q", got, expectedTable)
+	}
+}
+
+func TestCLI_processCoverage(t *testing.T) {
+	outStream, errStream := new(bytes.Buffer), new(bytes.Buffer)
+	cli := &CLI{outStream: outStream, errStream: errStream}
+	opts := &generateOpts{
+		diffOpts: &diffOpts{},
+		coverage: newCoverReport("cover.out", coverProfile{}, 50),
+	}
+
+	// Files which are not in the profile are reported and skipped.
+	if status := cli.processCoverage("p.go", "p_test.go", opts); status != ExitCodeOK {
+		t.Errorf("expected %d to eq %d", status, ExitCodeOK)
+	}
+
+	expected := "p.go: not in coverage profile\n"
+	if got := errStream.String(); got != expected {
+		t.Errorf("expected %q to eq %q", got, expected)
+	}
+	if len(opts.coverage.Functions) != 0 {
+		t.Errorf("expected %d to eq %d", len(opts.coverage.Functions), 0)
+	}
+}
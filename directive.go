@@ -0,0 +1,125 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"go/token"
+	"io/ioutil"
+	"strings"
+)
+
+// generateDirective is the directive of source files whose tests are
+// generated by -generate (e.g., `//gotests:generate -table -parallel`).
+// The options after it are used for the file.
+const generateDirective = "//gotests:generate"
+
+// modeOptions are the options which change what gotests does (instead of
+// how tests are generated). They are not allowed in the generate directive.
+var modeOptions = []string{
+	"diff", "d", "write", "w", "list", "l", "reverse", "r",
+	"orphans", "prune", "rename", "json", "line", "overlay", "interactive",
+	"summary", "report", "o", "baseline", "link", "coverprofile", "threshold",
+	"since", "staged", "changed-funcs", "check", "generate", "version", "v", "godoc",
+}
+
+// directiveOpts returns opts with the options of the generate directive
+// in the source file applied, and false if the file has no directive.
+// Only the options of generated tests (e.g., -table) are allowed in the
+// directive.
+func directiveOpts(srcPath string, opts *generateOpts) (*generateOpts, bool, error) {
+	goFile, err := parseFileOverlay(srcPath, opts.overlay)
+	if err != nil {
+		return nil, false, err
+	}
+
+	var directive *token.Position
+	var args []string
+	for _, group := range goFile.AstFile.Comments {
+		for _, c := range group.List {
+			if c.Text != generateDirective && !strings.HasPrefix(c.Text, generateDirective+" ") {
+				continue
+			}
+
+			pos := goFile.FSet.Position(c.Pos())
+			if directive != nil {
+				return nil, false, &posError{
+					Pos: pos,
+					Msg: "duplicate " + strings.TrimPrefix(generateDirective, "//") + " directive",
+				}
+			}
+			directive = &pos
+			args = strings.Fields(strings.TrimPrefix(c.Text, generateDirective))
+		}
+	}
+
+	if directive == nil {
+		return nil, false, nil
+	}
+
+	merged, err := applyDirective(opts, args)
+	if err != nil {
+		return nil, false, &posError{
+			Pos: *directive,
+			Msg: "invalid " + strings.TrimPrefix(generateDirective, "//") + " directive: " + err.Error(),
+		}
+	}
+	return merged, true, nil
+}
+
+// applyDirective returns the copy of opts with the options of directive
+// args applied. Options must be in the form of `-name` or `-name=value`.
+func applyDirective(opts *generateOpts, args []string) (*generateOpts, error) {
+	merged := *opts
+	diffOpts := *opts.diffOpts
+	merged.diffOpts = &diffOpts
+	merged.generate = false
+
+	for _, arg := range args {
+		name := strings.TrimLeft(arg, "-")
+		if i := strings.Index(name, "="); i >= 0 {
+			name = name[:i]
+		}
+
+		if !strings.HasPrefix(arg, "-") {
+			return nil, fmt.Errorf("options must be in the form of -name or -name=value: %s", arg)
+		}
+		if contains(modeOptions, name) {
+			return nil, fmt.Errorf("-%s is not allowed in the directive", name)
+		}
+	}
+
+	flags := flag.NewFlagSet(strings.TrimPrefix(generateDirective, "//"), flag.ContinueOnError)
+	flags.SetOutput(ioutil.Discard)
+
+	flags.BoolVar(&diffOpts.IncludeUnexported, "include-unexported", diffOpts.IncludeUnexported, "")
+	flags.BoolVar(&diffOpts.IncludeUnexported, "i", diffOpts.IncludeUnexported, "")
+
+	flags.Func("order", "", func(s string) error {
+		order, err := ParseOrder(s)
+		merged.order = order
+		return err
+	})
+
+	flags.BoolVar(&merged.examples, "examples", merged.examples, "")
+	flags.BoolVar(&merged.bench, "bench", merged.bench, "")
+	flags.BoolVar(&merged.fuzz, "fuzz", merged.fuzz, "")
+
+	flags.BoolVar(&merged.table, "table", merged.table, "")
+	flags.BoolVar(&merged.mocks, "mocks", merged.mocks, "")
+	flags.Func("assert", "", func(s string) error {
+		assert, err := ParseAssertStyle(s)
+		merged.assert = assert
+		return err
+	})
+	flags.BoolVar(&merged.parallel, "parallel", merged.parallel, "")
+	flags.BoolVar(&merged.golden, "golden", merged.golden, "")
+	flags.BoolVar(&merged.doc, "doc", merged.doc, "")
+
+	if err := flags.Parse(args); err != nil {
+		return nil, err
+	}
+
+	merged.impliesTable()
+
+	return &merged, nil
+}
@@ -0,0 +1,71 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestDirectiveOpts(t *testing.T) {
+	dir, err := ioutil.TempDir("", Name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	cases := []struct {
+		src   string
+		found bool
+		check func(opts *generateOpts) bool
+		err   string
+	}{
+		{"package p\n\nfunc Add() {}\n", false, nil, ""},
+		{"package p\n\n//gotests:generated\nfunc Add() {}\n", false, nil, ""},
+		{"package p\n\n//gotests:generate\nfunc Add() {}\n", true, func(opts *generateOpts) bool {
+			return !opts.generate && !opts.table && opts.order == Append
+		}, ""},
+		{"package p\n\n//gotests:generate -mocks -order=source -i\nfunc Add() {}\n", true, func(opts *generateOpts) bool {
+			return opts.mocks && opts.table && opts.order == SourceOrder && opts.diffOpts.IncludeUnexported
+		}, ""},
+		{"package p\n\n//gotests:generate -order source\n", false, nil,
+			":3:1: invalid gotests:generate directive: options must be in the form of -name or -name=value: source"},
+		{"package p\n\n//gotests:generate -generate\n", false, nil,
+			":3:1: invalid gotests:generate directive: -generate is not allowed in the directive"},
+		{"package p\n\n//gotests:generate -report=html\n", false, nil,
+			":3:1: invalid gotests:generate directive: -report is not allowed in the directive"},
+		{"package p\n\n//gotests:generate -nope\n", false, nil,
+			":3:1: invalid gotests:generate directive: flag provided but not defined: -nope"},
+		{"package p\n\n//gotests:generate\n\n//gotests:generate -table\n", false, nil,
+			":5:1: duplicate gotests:generate directive"},
+	}
+
+	path := filepath.Join(dir, "p.go")
+	for _, c := range cases {
+		if err := ioutil.WriteFile(path, []byte(c.src), 0644); err != nil {
+			t.Fatal(err)
+		}
+
+		opts := &generateOpts{diffOpts: &diffOpts{}, generate: true}
+		merged, found, err := directiveOpts(path, opts)
+		if c.err != "" {
+			if err == nil || err.Error() != path+c.err {
+				t.Errorf("expected %v to eq %q", err, path+c.err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatalf("directiveOpts failed: %s", err)
+		}
+
+		if found != c.found {
+			t.Errorf("expected %t to eq %t", found, c.found)
+		}
+		if c.check != nil && !c.check(merged) {
+			t.Errorf("unexpected opts of %q: %+v", c.src, merged)
+		}
+		if !opts.generate || opts.diffOpts.IncludeUnexported {
+			t.Errorf("expected opts not to be changed: %+v", opts)
+		}
+	}
+}
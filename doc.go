@@ -10,6 +10,27 @@ https://github.com/tcnksm/gotests
 Usage:
 
   gotests [options] PATH ...
+  gotests lsp [options]
+  gotests hook install [options] [PATH ...]
+
+  Errors are reported as 'path:line:col: message' (or 'path: message'
+  if there is no position in the file).
+
+Commands:
+
+  lsp            Run language server over stdio which offers code actions
+                 'Generate test for X' (for the function/method at the
+                 cursor) and 'Generate missing tests in file' on source
+                 files. Their edits target the test file. Options are
+                 applied to the generated tests.
+
+  hook install   Install git pre-commit hook (.git/hooks/pre-commit) which
+                 runs 'gotests -check -staged [options] [PATH ...]' (PATH
+                 is '.' by default) and rejects commits of functions and
+                 methods without tests. Existing hooks are not overwritten
+                 unless they are installed by gotests. For example,
+                 'gotests hook install -changed-funcs' checks only the
+                 changed functions/methods.
 
 Options:
 
@@ -23,6 +44,168 @@ Options:
 
   -i             Include unexport function/method for generating target.
 
+  -order=ORDER   Order to insert generated tests into existing test file.
+                 'append' (default) adds them to the end of the file,
+                 'source' inserts them next to the tests of neighboring
+                 source declarations and 'alpha' keeps tests sorted by name.
+
+  -table         Generate table-driven test body which calls the function
+                 with args of each test case and compares the results,
+                 instead of an empty test function. context.Context of
+                 the first param is created by context.Background() (or
+                 t.Context() if go.mod declares Go 1.24 or later) and
+                 a canceled context case is added (commented out with
+                 TODO) if it returns error.
+
+  -mocks         Generate fakes (structs with function fields) of interfaces
+                 declared in the source file or used as params into
+                 'mock_<file>_test.go' (<file> is the file declaring the
+                 interface), and use them in table-driven test args.
+                 Only interfaces declared in the package are faked: params
+                 of other packages' interfaces (e.g. io.Reader) get none,
+                 and methods of such embedded interfaces are not
+                 implemented (calling them panics). This implies -table.
+
+  -assert=STYLE  Style of comparisons in table-driven tests. 'std' (default)
+                 uses reflect.DeepEqual and t.Errorf, 'testify' uses
+                 assert.Equal and require.NoError, 'go-cmp' uses cmp.Diff
+                 and 'quicktest' uses qt.DeepEquals. This implies -table.
+
+  -parallel      Call t.Parallel() in generated tests and in each subtest
+                 of table-driven tests. The loop variable is captured
+                 ('tt := tt') unless go.mod declares Go 1.22 or later.
+
+  -golden        Compare the result of functions which return []byte, string
+                 or io.Reader with 'testdata/<TestName>/<case>.golden'.
+                 'go test -update' rewrites golden files with the results.
+                 With -w, empty golden files are also created. This implies
+                 -table.
+
+  -doc           Add the first sentence of the doc comment of the function
+                 above the generated test. With -table, test cases are
+                 also seeded from 'Example:' lines and bullet lines
+                 ('- ', '* ' or '+ ') of the doc comment.
+
+  -orphans       Report tests whose target functions/methods no longer exist
+                 in the package instead of generating tests. Test names
+                 are mapped back to targets by the naming rule (e.g.,
+                 'TestUser_Add' to 'User.Add'), ignoring the case of the
+                 first letters. Tests of variations (e.g.,
+                 'TestUser_Add_error') and tests which are not mapped back
+                 to exactly one target are not reported.
+
+  -prune         Delete empty orphaned tests. Orphaned tests with bodies
+                 are kept and reported.
+
+  -rename=OLD=NEW[,OLD=NEW...]
+                 Rename tests (and benchmarks, examples and fuzz tests) of
+                 renamed functions/methods (e.g., 'User.Add=User.Insert')
+                 in place, together with the calls of the target and its
+                 names in string literals (e.g., t.Run names) in them.
+
+  -json          Print the changes of files as JSON (one object per source
+                 file) instead of the content, for editor integration.
+                 Files are not written. Each change has edits (byte offset,
+                 line and column of start and end, and the new text) to
+                 apply from the last one, the added tests with their lines
+                 and, with -d, the diff. Syntax errors have positions:
+                 {"source": PATH, "files": [{"path": PATH, "create": BOOL,
+                 "edits": [...], "tests": [...], "diff": DIFF}],
+                 "errors": [{"path": PATH, "line": N, "col": N,
+                 "message": MSG}]}
+
+  -line=N        Generate only the test of the function/method declared
+                 at line N of the source file (e.g., the line at cursor).
+
+  -overlay=FILE  Read files (e.g., unsaved editor buffers) from the
+                 replacement files in FILE instead of their paths. The
+                 format is the same as 'go build -overlay':
+                 {"Replace": {PATH: REPLACEMENT_PATH}}
+
+  -interactive   List the functions/methods without tests with their
+                 signatures and let you toggle which tests to generate
+                 before generating them. The selected tests are written
+                 (as with -w) unless -d, -l or -json is given. If stdin is
+                 not a terminal, a line of the numbers to generate
+                 (e.g., '1 3 5-7') is read from it instead of prompting,
+                 and nothing is generated if it's empty.
+
+  -summary       Print the counts of exported/unexported functions and
+                 methods, how many of them have tests, how many tests would
+                 be generated and the percentage of the tested ones (test
+                 presence coverage) per package and overall, instead of
+                 generating tests. With -json, it's printed as JSON.
+
+  -report=FORMAT Write the report of functions/methods of each package and
+                 file with their statuses (tested, missing or orphaned) and
+                 the percentage of the tested ones, instead of generating
+                 tests. FORMAT is 'html' (browsable static page) or 'json'.
+
+  -o=FILE        Write the report to FILE instead of stdout.
+
+  -baseline=FILE Compare the coverage of the report with the JSON report
+                 saved in FILE (e.g., last week's one) to show the trend.
+
+  -link=URL      Link of functions in the HTML report. '{path}' (relative
+                 to the current directory) and '{line}' are replaced
+                 (default: '{path}#L{line}'). For example,
+                 'https://github.com/user/repo/blob/main/{path}#L{line}'.
+
+  -coverprofile=FILE
+                 Print the statement coverage of each function/method in
+                 the coverage profile FILE ('go test -coverprofile=FILE')
+                 with its test, instead of generating tests. Functions are
+                 sorted by priority to generate tests: 'uncovered' ones
+                 (no statement covered) first and then 'low' ones (below
+                 -threshold), and the ones without tests before the ones
+                 with tests. With -json, it's printed as JSON.
+
+  -threshold=N   Coverage percentage below which functions are flagged
+                 'low' with -coverprofile (default: 50).
+
+  -since=REF     Process only the source files changed since git REF
+                 (e.g., 'main' or 'HEAD~3') in the working tree, as listed
+                 by 'git diff --name-only REF'. PATHs are still walked but
+                 the other files are skipped.
+
+  -staged        Process only the source files whose changes are staged
+                 ('git diff --cached --name-only'), e.g., in pre-commit
+                 hooks. With -since, they are compared with REF instead
+                 of HEAD.
+
+  -changed-funcs With -since or -staged, generate (or check) only the tests
+                 of the functions/methods whose lines (or doc comments)
+                 changed.
+
+  -check         Print the functions/methods without tests as
+                 'path:line: Target: TestName is missing' instead of
+                 generating tests, and exit with non-zero status if there
+                 is any (e.g., in pre-commit hooks or CI).
+
+  -generate      Generate tests only of the source files which have the
+                 '//gotests:generate [options]' directive, with the
+                 options of the command line and the directive (e.g.,
+                 '//gotests:generate -table -parallel'). Directive options
+                 must be in the form of -name or -name=value and only the
+                 options of generated tests (-i, -order, -examples, -bench,
+                 -fuzz, -table, -mocks, -assert, -parallel, -golden and
+                 -doc) are allowed. Tests are
+                 written (as with -w) unless -d, -l, -check or -json is
+                 given. Without PATHs, the directory of $GOFILE is used,
+                 so that 'go generate' keeps tests scaffolded with
+                 '//go:generate gotests -generate' in the package.
+
+  -examples      Also generate example functions (ExampleX, ExampleT_M)
+                 for exported functions/methods which don't have one in
+                 any test file of the package.
+
+  -bench         Also generate benchmark functions (BenchmarkX, BenchmarkT_M)
+                 which call the function with zero value args in the loop.
+                 b.Loop is used when go.mod declares Go 1.24 or later.
+
+  -fuzz          Also generate fuzz tests (FuzzX) for functions whose params
+                 are all fuzzable types (string, []byte, bool, ints and floats).
+
   -reverse, -r   (experimental) Allow to provide test file instead of source file.
                  By default, gotests expects source file PATH provided.
                  With this flag, the test file can be given. 
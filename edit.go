@@ -0,0 +1,371 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"go/ast"
+	"go/format"
+	"go/parser"
+	"go/token"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"golang.org/x/tools/go/ast/astutil"
+	"golang.org/x/tools/imports"
+)
+
+// endOfFile is edit offset which means the end of the source.
+const endOfFile = -1
+
+// edit is text inserted into the original source at Offset.
+// If End is set, the text replaces the source between Offset and End.
+// Generate applies edits instead of re-printing the whole AST
+// so that comments and formatting of the existing file are kept.
+type edit struct {
+	Offset int
+	End    int
+	Text   []byte
+}
+
+// applyEdits inserts edits into src. Edits at the same offset are
+// inserted in the order they are given. Edits inside the range replaced
+// by another edit are inserted at the end of the range.
+func applyEdits(src []byte, edits []*edit) []byte {
+	sorted := make([]*edit, len(edits))
+	copy(sorted, edits)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return offsetOf(src, sorted[i]) < offsetOf(src, sorted[j])
+	})
+
+	var buf bytes.Buffer
+	last := 0
+	for _, e := range sorted {
+		offset := offsetOf(src, e)
+		if offset > last {
+			buf.Write(src[last:offset])
+			last = offset
+		}
+		buf.Write(e.Text)
+		if e.End > last {
+			last = e.End
+		}
+	}
+	buf.Write(src[last:])
+
+	return buf.Bytes()
+}
+
+func offsetOf(src []byte, e *edit) int {
+	if e.Offset == endOfFile || e.Offset > len(src) {
+		return len(src)
+	}
+	return e.Offset
+}
+
+// formatDecl returns gofmt-ed source of the given declaration.
+func formatDecl(decl ast.Decl) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := format.Node(&buf, token.NewFileSet(), decl); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// formatFuncDecl returns gofmt-ed source of the given function
+// declaration whose body is replaced with body source.
+func formatFuncDecl(decl *ast.FuncDecl, body string) ([]byte, error) {
+	text, err := formatDecl(decl)
+	if err != nil {
+		return nil, err
+	}
+
+	body = strings.Trim(body, "\n")
+	if body == "" {
+		return text, nil
+	}
+
+	// Printed decl always ends with the closing brace of empty body.
+	idx := bytes.LastIndexByte(text, '}')
+	if idx < 0 {
+		return nil, fmt.Errorf("invalid func decl: %s", text)
+	}
+
+	var buf bytes.Buffer
+	buf.Write(text[:idx])
+	buf.WriteString(body)
+	buf.WriteString("\n}")
+
+	return format.Source(buf.Bytes())
+}
+
+// missingImports returns import specs which are required by decls
+// but not yet imported by file. It asks goimports which packages
+// decls need and drops the ones which file already has. extra are
+// imports which goimports should use if decls need them.
+func missingImports(filename string, file *ast.File, extra []*ast.ImportSpec, decls []byte) ([]*ast.ImportSpec, error) {
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "package %s\n\n", file.Name.Name)
+	for _, spec := range file.Imports {
+		fmt.Fprintf(&buf, "import %s\n", importSpecText(spec))
+	}
+	for _, spec := range extra {
+		fmt.Fprintf(&buf, "import %s\n", importSpecText(spec))
+	}
+	buf.WriteString("\n")
+	buf.Write(decls)
+
+	res, err := imports.Process(filename, buf.Bytes(), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	f, err := parser.ParseFile(token.NewFileSet(), filename, res, parser.ImportsOnly)
+	if err != nil {
+		return nil, err
+	}
+
+	existing := make(map[string]bool, len(file.Imports))
+	for _, spec := range file.Imports {
+		existing[spec.Path.Value] = true
+	}
+
+	var missing []*ast.ImportSpec
+	for _, spec := range f.Imports {
+		if !existing[spec.Path.Value] {
+			missing = append(missing, spec)
+		}
+	}
+
+	return missing, nil
+}
+
+// importEdit returns edit which adds specs to the import declarations
+// of file. If file already has a grouped import, specs are added
+// to the last group. If file has only one single import, it's replaced
+// with grouped one (without it if it's unused) where standard library
+// packages are grouped separately. Otherwise new import
+// declarations are added after the last import (or package clause).
+func importEdit(fset *token.FileSet, file *ast.File, specs []*ast.ImportSpec, unused map[*ast.ImportSpec]bool) *edit {
+	var importDecls []*ast.GenDecl
+	for _, decl := range file.Decls {
+		if genDecl, ok := decl.(*ast.GenDecl); ok && genDecl.Tok == token.IMPORT {
+			importDecls = append(importDecls, genDecl)
+		}
+	}
+
+	var lastImport *ast.GenDecl
+	if len(importDecls) > 0 {
+		lastImport = importDecls[len(importDecls)-1]
+	}
+
+	var buf bytes.Buffer
+	switch {
+	case len(importDecls) == 1 && !lastImport.Lparen.IsValid():
+		// Replace the single import with grouped one like goimports does.
+		if spec := lastImport.Specs[0].(*ast.ImportSpec); !unused[spec] {
+			specs = append([]*ast.ImportSpec{spec}, specs...)
+		}
+		sort.SliceStable(specs, func(i, j int) bool {
+			return specs[i].Path.Value < specs[j].Path.Value
+		})
+
+		if len(specs) == 1 {
+			return &edit{
+				Offset: fset.Position(lastImport.Pos()).Offset,
+				End:    fset.Position(lastImport.End()).Offset,
+				Text:   []byte("import " + importSpecText(specs[0])),
+			}
+		}
+
+		writeImportGroups(&buf, specs)
+		return &edit{
+			Offset: fset.Position(lastImport.Pos()).Offset,
+			End:    fset.Position(lastImport.End()).Offset,
+			Text:   buf.Bytes(),
+		}
+
+	case lastImport != nil && lastImport.Lparen.IsValid():
+		for _, spec := range specs {
+			fmt.Fprintf(&buf, "\t%s\n", importSpecText(spec))
+		}
+		return &edit{
+			Offset: fset.Position(lastImport.Rparen).Offset,
+			Text:   buf.Bytes(),
+		}
+
+	case lastImport != nil:
+		for _, spec := range specs {
+			fmt.Fprintf(&buf, "\nimport %s", importSpecText(spec))
+		}
+		return &edit{
+			Offset: fset.Position(lastImport.End()).Offset,
+			Text:   buf.Bytes(),
+		}
+
+	default:
+		if len(specs) == 1 {
+			fmt.Fprintf(&buf, "\n\nimport %s", importSpecText(specs[0]))
+		} else {
+			buf.WriteString("\n\n")
+			writeImportGroups(&buf, specs)
+		}
+		return &edit{
+			Offset: fset.Position(file.Name.End()).Offset,
+			Text:   buf.Bytes(),
+		}
+	}
+}
+
+// writeImportGroups writes grouped import declaration of specs. Like
+// goimports, standard library packages are grouped before the others
+// with a blank line between them.
+func writeImportGroups(buf *bytes.Buffer, specs []*ast.ImportSpec) {
+	var std, others []*ast.ImportSpec
+	for _, spec := range specs {
+		if isStdImport(spec) {
+			std = append(std, spec)
+		} else {
+			others = append(others, spec)
+		}
+	}
+
+	buf.WriteString("import (\n")
+	for _, spec := range std {
+		fmt.Fprintf(buf, "\t%s\n", importSpecText(spec))
+	}
+	if len(std) > 0 && len(others) > 0 {
+		buf.WriteString("\n")
+	}
+	for _, spec := range others {
+		fmt.Fprintf(buf, "\t%s\n", importSpecText(spec))
+	}
+	buf.WriteString(")")
+}
+
+// isStdImport returns true if spec imports a standard library package
+// (the first element of whose path has no dot).
+func isStdImport(spec *ast.ImportSpec) bool {
+	path, err := strconv.Unquote(spec.Path.Value)
+	if err != nil {
+		return false
+	}
+	if i := strings.Index(path, "/"); i >= 0 {
+		path = path[:i]
+	}
+	return !strings.Contains(path, ".")
+}
+
+// unusedImports returns the imports of file which are not used in res,
+// the source of file with edits applied (e.g., imports used only by
+// removed declarations). Imports which file does not use either (e.g.,
+// blank imports or imports whose names are not guessed from their paths)
+// are kept.
+func unusedImports(filename string, file *ast.File, res []byte) (map[*ast.ImportSpec]bool, error) {
+	f, err := parser.ParseFile(token.NewFileSet(), filename, res, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	unused := make(map[*ast.ImportSpec]bool)
+	for _, spec := range file.Imports {
+		path, err := strconv.Unquote(spec.Path.Value)
+		if err != nil {
+			continue
+		}
+		if astutil.UsesImport(file, path) && !astutil.UsesImport(f, path) {
+			unused[spec] = true
+		}
+	}
+	return unused, nil
+}
+
+// deleteImportEdits returns edits which delete unused specs from the
+// import declarations of file in src. Declarations whose specs are all
+// unused are deleted with the blank lines before them. If adding is true,
+// the declaration which importEdit adds imports to is kept: the last
+// grouped one loses only its unused lines and the only single one is
+// replaced by importEdit.
+func deleteImportEdits(fset *token.FileSet, src []byte, file *ast.File, unused map[*ast.ImportSpec]bool, adding bool) []*edit {
+	var importDecls []*ast.GenDecl
+	for _, decl := range file.Decls {
+		if genDecl, ok := decl.(*ast.GenDecl); ok && genDecl.Tok == token.IMPORT {
+			importDecls = append(importDecls, genDecl)
+		}
+	}
+
+	var edits []*edit
+	for i, decl := range importDecls {
+		last := i == len(importDecls)-1
+		if adding && len(importDecls) == 1 && !decl.Lparen.IsValid() {
+			continue
+		}
+
+		all := true
+		for _, spec := range decl.Specs {
+			all = all && unused[spec.(*ast.ImportSpec)]
+		}
+
+		if all && !(adding && last && decl.Lparen.IsValid()) {
+			start, end := fset.Position(decl.Pos()).Offset, fset.Position(decl.End()).Offset
+			if decl.Doc != nil {
+				start = fset.Position(decl.Doc.Pos()).Offset
+			}
+			for start > 0 && unicode.IsSpace(rune(src[start-1])) {
+				start--
+			}
+			edits = append(edits, &edit{Offset: start, End: end})
+			continue
+		}
+
+		for _, s := range decl.Specs {
+			spec := s.(*ast.ImportSpec)
+			if !unused[spec] {
+				continue
+			}
+
+			// Delete the lines of the spec (with its comments).
+			start, end := fset.Position(spec.Pos()).Offset, fset.Position(spec.End()).Offset
+			if spec.Doc != nil {
+				start = fset.Position(spec.Doc.Pos()).Offset
+			}
+			if spec.Comment != nil {
+				end = fset.Position(spec.Comment.End()).Offset
+			}
+			for start > 0 && (src[start-1] == ' ' || src[start-1] == '\t') {
+				start--
+			}
+			if i := bytes.IndexByte(src[end:], '\n'); i >= 0 {
+				end += i + 1
+			}
+			edits = append(edits, &edit{Offset: start, End: end})
+		}
+	}
+
+	return edits
+}
+
+// newImportSpec creates ImportSpec of path. name is empty
+// if it's not renamed.
+func newImportSpec(name, path string) *ast.ImportSpec {
+	spec := &ast.ImportSpec{
+		Path: &ast.BasicLit{Kind: token.STRING, Value: strconv.Quote(path)},
+	}
+	if name != "" {
+		spec.Name = ast.NewIdent(name)
+	}
+	return spec
+}
+
+func importSpecText(spec *ast.ImportSpec) string {
+	path, err := strconv.Unquote(spec.Path.Value)
+	if err != nil {
+		path = spec.Path.Value
+	}
+
+	if spec.Name != nil {
+		return fmt.Sprintf("%s %q", spec.Name.Name, path)
+	}
+	return strconv.Quote(path)
+}
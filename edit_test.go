@@ -0,0 +1,96 @@
+package main
+
+import (
+	"go/ast"
+	"strings"
+	"testing"
+)
+
+func TestApplyEdits(t *testing.T) {
+	src := []byte("abcdef")
+	edits := []*edit{
+		{Offset: endOfFile, Text: []byte("Z")},
+		{Offset: 3, Text: []byte("1")},
+		{Offset: 0, Text: []byte("0")},
+		{Offset: 3, Text: []byte("2")},
+	}
+
+	expected := "0abc12defZ"
+	if res := string(applyEdits(src, edits)); res != expected {
+		t.Errorf("expected %q to eq %q", res, expected)
+	}
+}
+
+func TestDeleteImportEdits(t *testing.T) {
+	src := `package p
+
+import (
+	"fmt"
+	// reflect is for DeepEqual.
+	"reflect" // comment
+	"strings"
+)
+
+import "os"
+
+func  keep()  { fmt.Println(strings.ToUpper("")) }
+`
+	goFile, err := parse("p.go", strings.NewReader(src))
+	if err != nil {
+		t.Fatalf("parse failed: %s", err)
+	}
+
+	unused := make(map[*ast.ImportSpec]bool)
+	for _, spec := range goFile.AstFile.Imports {
+		if spec.Path.Value == `"reflect"` || spec.Path.Value == `"os"` {
+			unused[spec] = true
+		}
+	}
+
+	edits := deleteImportEdits(goFile.FSet, goFile.SrcBytes, goFile.AstFile, unused, false)
+	expected := `package p
+
+import (
+	"fmt"
+	"strings"
+)
+
+func  keep()  { fmt.Println(strings.ToUpper("")) }
+`
+	if res := string(applyEdits(goFile.SrcBytes, edits)); res != expected {
+		t.Errorf("expected %q to eq %q", res, expected)
+	}
+}
+
+func TestImportEdit(t *testing.T) {
+	src := `package p
+
+import "github.com/google/go-cmp/cmp"
+
+var _ = cmp.Equal
+`
+	goFile, err := parse("p_test.go", strings.NewReader(src))
+	if err != nil {
+		t.Fatalf("parse failed: %s", err)
+	}
+
+	specs := []*ast.ImportSpec{newImportSpec("", "testing"), newImportSpec("", "context")}
+	e := importEdit(goFile.FSet, goFile.AstFile, specs, nil)
+
+	// Standard library packages are grouped before the others
+	// so that goimports does not rewrite them.
+	expected := `package p
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+var _ = cmp.Equal
+`
+	if res := string(applyEdits(goFile.SrcBytes, []*edit{e})); res != expected {
+		t.Errorf("expected %q to eq %q", res, expected)
+	}
+}
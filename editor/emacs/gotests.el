@@ -4,18 +4,191 @@
 
 ;; Author: Taichi Nakashima
 ;; Keywords: go, languages
+;; Package-Requires: ((emacs "27.1"))
 
-(defun gotests()
-  (interactive)    
-  (let ((buf (get-buffer-create "*Gotests patch*"))        
-        (coding-system-for-read 'utf-8)
-        (coding-system-for-write 'utf-8))
-    
+;;; Commentary:
+
+;; `M-x gotests' generates the test of the function at point of the
+;; source buffer (or the missing tests of the file with a prefix
+;; argument or in the test buffer). The changes are shown as a diff
+;; first and applied to the buffers (not to the files) by `C-c C-c'.
+;; `C-c C-k' rejects them.
+;;
+;; Unsaved buffers of the source and test files are given to gotests
+;; via -overlay, so their unsaved changes are kept.
+
+;;; Code:
+
+(require 'diff-mode)
+(require 'json)
+
+(defgroup gotests nil
+  "Generate Go tests with gotests."
+  :group 'go)
+
+(defcustom gotests-command "gotests"
+  "The gotests command."
+  :type 'string
+  :group 'gotests)
+
+(defcustom gotests-args nil
+  "Additional options given to gotests (e.g., (\"-table\"))."
+  :type '(repeat string)
+  :group 'gotests)
+
+(defvar-local gotests--result nil
+  "The files of the -json result shown in the diff buffer.")
+
+(defvar-local gotests--ticks nil
+  "The modification ticks of the buffers when gotests ran.")
+
+(defvar gotests-diff-mode-map
+  (let ((map (make-sparse-keymap)))
+    (define-key map (kbd "C-c C-c") #'gotests-apply)
+    (define-key map (kbd "C-c C-k") #'gotests-reject)
+    map)
+  "Keymap for `gotests-diff-mode'.")
+
+(define-derived-mode gotests-diff-mode diff-mode "Gotests-Diff"
+  "Major mode to review the changes of gotests.
+\\<gotests-diff-mode-map>Apply them with \\[gotests-apply] or reject them with \\[gotests-reject]."
+  (setq buffer-read-only t))
+
+(defun gotests--test-file-p (file)
+  (string-match-p "_test\\.go\\'" file))
+
+(defun gotests--test-file (file)
+  (concat (file-name-sans-extension file) "_test.go"))
+
+(defun gotests--overlay (files)
+  "Write the modified buffers visiting FILES to temporary files.
+Return (OVERLAY . TEMP-FILES) where OVERLAY is the file for -overlay,
+or nil if there is no modified buffer."
+  (let (replace)
+    (dolist (file files)
+      (let ((buf (find-buffer-visiting file)))
+        (when (and buf (buffer-modified-p buf))
+          (let ((tmp (make-temp-file "gotests" nil ".go")))
+            (with-current-buffer buf
+              (let ((coding-system-for-write 'utf-8-unix))
+                (write-region nil nil tmp nil 'silent)))
+            (push (cons (expand-file-name file) tmp) replace)))))
+    (when replace
+      (let ((overlay (make-temp-file "gotests" nil ".json")))
+        (with-temp-file overlay
+          (insert (json-encode `((Replace . ,replace)))))
+        (cons overlay (mapcar #'cdr replace))))))
+
+(defun gotests--run (file line)
+  "Run gotests -json on FILE and return the parsed result.
+If LINE is non-nil, only the test of the function at LINE is generated."
+  (let* ((test (gotests--test-file-p file))
+         (src (if test
+                  (replace-regexp-in-string "_test\\.go\\'" ".go" file)
+                file))
+         (overlay (gotests--overlay (list src (gotests--test-file src))))
+         (args (append '("-json" "-d") gotests-args
+                       (and line (list "-line" (number-to-string line)))
+                       (and overlay (list "-overlay" (car overlay)))
+                       (and test '("-r"))
+                       (list file)))
+         (coding-system-for-read 'utf-8)
+         (coding-system-for-write 'utf-8)
+         (err-file (make-temp-file "gotests")))
+    (unwind-protect
+        (with-temp-buffer
+          (apply #'call-process gotests-command nil (list t err-file) nil args)
+          (when (= (point-min) (point-max))
+            (error "gotests: %s"
+                   (with-temp-buffer
+                     (insert-file-contents err-file)
+                     (string-trim (buffer-string)))))
+          (goto-char (point-min))
+          (json-parse-buffer :object-type 'alist :array-type 'list
+                             :null-object nil :false-object nil))
+      (delete-file err-file)
+      (when overlay
+        (mapc #'delete-file (cons (car overlay) (cdr overlay)))))))
+
+;;;###autoload
+(defun gotests (&optional all)
+  "Generate the test of the function at point and show the diff.
+With prefix argument ALL, or in a test file, generate the missing tests
+of the file."
+  (interactive "P")
+  (let* ((file (or (buffer-file-name) (user-error "Buffer is not visiting a file")))
+         (line (unless (or all (gotests--test-file-p file))
+                 (line-number-at-pos)))
+         (result (gotests--run file line))
+         (files (alist-get 'files result)))
+    (when-let ((err (car (alist-get 'errors result))))
+      (user-error "gotests: %s" (alist-get 'message err)))
+    (if (null files)
+        (message "No tests to generate")
+      (gotests--show files))))
+
+(defun gotests--show (files)
+  "Show the diffs of FILES in the diff buffer."
+  (let ((buf (get-buffer-create "*gotests diff*")))
     (with-current-buffer buf
-      (erase-buffer))
+      (let ((inhibit-read-only t))
+        (erase-buffer)
+        (dolist (f files)
+          (insert (alist-get 'diff f))))
+      (gotests-diff-mode)
+      (setq gotests--result files)
+      (setq gotests--ticks
+            (mapcar (lambda (f)
+                      (let ((b (find-buffer-visiting (alist-get 'path f))))
+                        (cons (alist-get 'path f)
+                              (and b (buffer-chars-modified-tick b)))))
+                    files))
+      (goto-char (point-min)))
+    (pop-to-buffer buf)
+    (message (substitute-command-keys
+              "\\<gotests-diff-mode-map>\\[gotests-apply] to apply, \\[gotests-reject] to reject"))))
+
+(defun gotests--apply-edits (buf edits)
+  "Apply EDITS (sorted by offset) of -json to BUF from the last one."
+  (with-current-buffer buf
+    (save-excursion
+      (dolist (e (reverse edits))
+        (let ((start (byte-to-position (1+ (alist-get 'offset (alist-get 'start e)))))
+              (end (byte-to-position (1+ (alist-get 'offset (alist-get 'end e))))))
+          (goto-char start)
+          (delete-region start end)
+          (insert (alist-get 'text e)))))))
+
+(defun gotests-apply ()
+  "Apply the changes shown in the diff buffer to the buffers."
+  (interactive)
+  (let ((files gotests--result)
+        (ticks gotests--ticks)
+        first)
+    (unless files
+      (user-error "Nothing to apply"))
+    (dolist (tick ticks)
+      (let ((b (find-buffer-visiting (car tick))))
+        (when (and b (cdr tick) (/= (cdr tick) (buffer-chars-modified-tick b)))
+          (user-error "%s was changed after generating; run gotests again" (car tick)))))
+    (dolist (f files)
+      (let ((buf (find-file-noselect (alist-get 'path f))))
+        (gotests--apply-edits buf (alist-get 'edits f))
+        (unless first
+          (setq first (cons buf (alist-get 'line (car (alist-get 'tests f))))))))
+    (quit-window t)
+    (when first
+      (pop-to-buffer (car first))
+      (when (cdr first)
+        (goto-char (point-min))
+        (forward-line (1- (cdr first)))))))
+
+(defun gotests-reject ()
+  "Discard the changes shown in the diff buffer."
+  (interactive)
+  (quit-window t)
+  (message "Rejected"))
+
+(provide 'gotests)
 
-    ;; Only run when buffer is test file
-    (if (string-match-p "_test\\.go\\'" (buffer-file-name))
-        (progn
-          (call-process "gotests" nil buf nil "-w" "-r" (buffer-file-name))
-          (revert-buffer :ignore-auto :noconfirm)))))
+;;; gotests.el ends here
@@ -6,13 +6,32 @@
 "
 "   :Gotests
 "
-"       Filter the current Go buffer through gotests.
+"       Generate the test of the function (or method) under the cursor
+"       and open the test file at the new test. In a test file, or with
+"       a bang (:Gotests!), generate all missing tests of the file.
+"       Errors (e.g., syntax errors) are put into the quickfix list.
+"
+"       Unsaved changes of the source and test buffers are kept: gotests
+"       reads the buffers (-overlay) and returns the edits (-json) which
+"       are applied to the buffers. The buffers are not saved.
 "
 " Options:
 "
 "   g:go_tests_commands [default=1]
 "
 "       Flag to enable the gotests commands.
+"
+"   g:gotests_bin [default='gotests']
+"
+"       The gotests command.
+"
+"   g:gotests_args [default=[]]
+"
+"       Additional options given to gotests (e.g., ['-table']).
+"
+"   g:gotests_open [default='split']
+"
+"       Command to open the test file (e.g., 'vsplit', 'tabedit' or 'edit').
 
 if exists("b:did_ftplugin_go_tests")
     finish
@@ -22,34 +41,178 @@ if !exists("g:go_tests_commands")
     let g:go_tests_commands = 1
 endif
 
+if !exists("g:gotests_bin")
+    let g:gotests_bin = 'gotests'
+endif
+
+if !exists("g:gotests_args")
+    let g:gotests_args = []
+endif
+
+if !exists("g:gotests_open")
+    let g:gotests_open = 'split'
+endif
+
 if g:go_tests_commands
-    command! -buffer Gotests call s:GoTests()
+    command! -buffer -bang Gotests call s:GoTests(<bang>0)
 endif
 
-function! s:GoTests()
-    let view = winsaveview()
-    silent execute "%!gotests -r " . expand('%:p')
-    if v:shell_error
-        let errors = []
-        for line in getline(1, line('$'))
-            let tokens = matchlist(line, '^\(.\{-}\):\s*\(.\{-}\):\s*\(.\{-}\):\(\d\+\):\(\d\+\)\s*\(.*\)')
-            if !empty(tokens)
-                call add(errors, {"filename": @%,
-                                 \"lnum":     tokens[4],
-                                 \"col":      tokens[5],
-                                 \"text":     tokens[6]})
-            endif
-        endfor
-        if empty(errors)
-            % | " Couldn't detect gotests error format, output errors
+function! s:GoTests(all) abort
+    let path = expand('%:p')
+    let test = path =~# '_test\.go$'
+    let src = test ? substitute(path, '_test\.go$', '.go', '') : path
+
+    let args = ['-json'] + g:gotests_args
+    if !a:all && !test
+        let args += ['-line', string(line('.'))]
+    endif
+    if test
+        let args += ['-r']
+    endif
+
+    let tmps = []
+    let overlay = s:Overlay([src, substitute(src, '\.go$', '_test.go', '')], tmps)
+    if overlay !=# ''
+        let args += ['-overlay', overlay]
+    endif
+
+    let errfile = tempname()
+    call add(tmps, errfile)
+    let cmd = join(map([g:gotests_bin] + args + [path], 'shellescape(v:val)'))
+    let out = system(cmd . ' 2>' . shellescape(errfile))
+    let stderr = join(readfile(errfile), "\n")
+    for tmp in tmps
+        call delete(tmp)
+    endfor
+
+    if out ==# ''
+        echohl ErrorMsg | echomsg 'Gotests failed: ' . stderr | echohl None
+        return
+    endif
+
+    let result = json_decode(out)
+    if has_key(result, 'errors')
+        call s:SetErrors(result.errors)
+        return
+    endif
+
+    if empty(result.files)
+        echo 'No tests to generate'
+        return
+    endif
+
+    call s:Apply(result.files)
+endfunction
+
+" s:Overlay writes the modified buffers of paths to temporary files
+" (added to tmps) and returns the overlay file for -overlay. It returns
+" empty string if there is no modified buffer.
+function! s:Overlay(paths, tmps) abort
+    let replace = {}
+    for path in a:paths
+        let nr = bufnr(path)
+        if nr == -1 || !getbufvar(nr, '&modified')
+            continue
         endif
-        undo
-        if !empty(errors)
-            call setqflist(errors, 'r')
+        let tmp = tempname()
+        call writefile(getbufline(nr, 1, '$'), tmp)
+        call add(a:tmps, tmp)
+        let replace[path] = tmp
+    endfor
+
+    if empty(replace)
+        return ''
+    endif
+
+    let overlay = tempname()
+    call writefile([json_encode({'Replace': replace})], overlay)
+    call add(a:tmps, overlay)
+    return overlay
+endfunction
+
+" s:SetErrors puts errors of -json into the quickfix list.
+function! s:SetErrors(errors) abort
+    let items = []
+    for e in a:errors
+        call add(items, {'filename': e.path,
+                        \'lnum': get(e, 'line', 0),
+                        \'col': get(e, 'col', 0),
+                        \'text': e.message})
+    endfor
+    call setqflist(items, 'r')
+    copen
+    echohl ErrorMsg | echomsg 'Gotests failed: ' . a:errors[0].message | echohl None
+endfunction
+
+" s:Apply applies the changes of files to their buffers and shows the
+" first one (the test file) at the new test.
+function! s:Apply(files) abort
+    let first = a:files[0]
+    let winid = bufwinid(first.path)
+    if winid != -1
+        call win_gotoid(winid)
+    elseif expand('%:p') !=# first.path
+        execute g:gotests_open fnameescape(first.path)
+    endif
+    let nr = bufnr('%')
+
+    for f in a:files
+        if f.path !=# first.path
+            execute 'hide edit' fnameescape(f.path)
         endif
-        echohl ErrorMsg | echomsg "Gotests failed to run." | echohl None
+        for e in reverse(copy(f.edits))
+            call s:ApplyEdit(e)
+        endfor
+    endfor
+
+    execute 'hide buffer' nr
+    if has_key(first, 'tests') && !empty(first.tests)
+        call cursor(first.tests[0].line, 1)
+        normal! zz
+    endif
+endfunction
+
+" s:ApplyEdit applies the edit of -json to the current buffer.
+" Line and col of its positions are 1-based and col is in bytes.
+function! s:ApplyEdit(edit) abort
+    let start = a:edit.start
+    let end = a:edit.end
+    let last = line('$')
+    let blank = last == 1 && getline(1) ==# ''
+
+    " text is the lines in the range of the edit (with their newlines).
+    let lines = []
+    if !blank && start.line <= last
+        let lines = getline(start.line, min([end.line, last]))
+    endif
+    let text = empty(lines) ? '' : join(lines, "\n") . "\n"
+
+    " Offsets of the start and the end in text.
+    let soff = empty(lines) ? 0 : start.col - 1
+    let eoff = len(text)
+    if end.line <= last && !empty(lines)
+        let eoff = 0
+        for i in range(end.line - start.line)
+            let eoff += len(lines[i]) + 1
+        endfor
+        let eoff += end.col - 1
+    endif
+
+    let replaced = split(strpart(text, 0, soff) . a:edit.text . strpart(text, eoff), "\n", 1)
+    if !empty(replaced) && replaced[-1] ==# ''
+        call remove(replaced, -1)
+    endif
+
+    if !empty(lines)
+        silent execute start.line . ',' . (start.line + len(lines) - 1) . 'delete _'
+    endif
+
+    if blank || len(lines) == last
+        " The whole buffer is replaced.
+        call setline(1, replaced)
+    else
+        call append(start.line - 1, replaced)
     endif
-    call winrestview(view)
 endfunction
 
 let b:did_ftplugin_go_tests = 1
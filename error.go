@@ -0,0 +1,59 @@
+package main
+
+import (
+	"errors"
+	"go/scanner"
+	"go/token"
+	"os"
+)
+
+// posError is an error at the position of a file.
+type posError struct {
+	Pos token.Position
+	Msg string
+}
+
+// Error returns the error in the form of `path:line:col: message`.
+func (e *posError) Error() string {
+	return e.Pos.String() + ": " + e.Msg
+}
+
+// positionedErrors returns err of processing the file of path as errors
+// at their positions. Syntax errors (scanner.ErrorList) and posError have
+// their own positions (syntax errors of generated source are at the file
+// of path without line and column), errors of files (os.PathError) are at the files
+// and the others are at the file of path without line and column.
+func positionedErrors(path string, err error) []*posError {
+	var list scanner.ErrorList
+	if errors.As(err, &list) && len(list) > 0 {
+		errs := make([]*posError, 0, len(list))
+		for _, e := range list {
+			pos := e.Pos
+			if pos.Filename == "" {
+				// Generated source is parsed without file name. Its
+				// lines and columns are not the ones of the file.
+				pos = token.Position{Filename: path}
+			}
+			errs = append(errs, &posError{Pos: pos, Msg: e.Msg})
+		}
+		return errs
+	}
+
+	var pe *posError
+	if errors.As(err, &pe) {
+		return []*posError{pe}
+	}
+
+	var pathErr *os.PathError
+	if errors.As(err, &pathErr) {
+		return []*posError{{
+			Pos: token.Position{Filename: pathErr.Path},
+			Msg: pathErr.Op + ": " + pathErr.Err.Error(),
+		}}
+	}
+
+	return []*posError{{
+		Pos: token.Position{Filename: path},
+		Msg: err.Error(),
+	}}
+}
@@ -0,0 +1,52 @@
+package main
+
+import (
+	"fmt"
+	"go/token"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestPositionedErrors(t *testing.T) {
+	_, syntaxErr := parse("p.go", strings.NewReader("package p\n\nfunc Add( {}\n"))
+	_, genErr := parse("", strings.NewReader("package p\n\nfunc Add( {}\n"))
+	_, statErr := os.Stat("no_such_file.go")
+
+	cases := []struct {
+		err      error
+		expected string
+	}{
+		{
+			fmt.Errorf("failed to parse go file: %w", syntaxErr),
+			"p.go:3:11: expected ')', found '{'",
+		},
+		{
+			// Generated source has no file name.
+			genErr,
+			"src.go: expected ')', found '{'",
+		},
+		{
+			&posError{Pos: token.Position{Filename: "p.go", Line: 2, Column: 1}, Msg: "no function"},
+			"p.go:2:1: no function",
+		},
+		{
+			statErr,
+			"no_such_file.go: stat: no such file or directory",
+		},
+		{
+			fmt.Errorf("failed to add fakes"),
+			"src.go: failed to add fakes",
+		},
+	}
+
+	for _, c := range cases {
+		var res []string
+		for _, e := range positionedErrors("src.go", c.err) {
+			res = append(res, e.Error())
+		}
+		if got := strings.Join(res, "\n"); got != c.expected {
+			t.Errorf("expected %q to eq %q", got, c.expected)
+		}
+	}
+}
@@ -0,0 +1,49 @@
+package main
+
+import (
+	"bytes"
+	"text/template"
+)
+
+var (
+	defaultExpectExampleFuncTmpl       = "Example{{ .Name }}"
+	defaultExpectExampleFuncMethodTmpl = "Example{{ .RecvName }}_{{ .Name }}"
+)
+
+// exampleBodyTmpl is the body of generated example function. It calls
+// the target with zero values and prints the results (unless they are
+// functions).
+var exampleBodyTmpl = template.Must(template.New("example").Parse(`
+{{- range .Vars }}
+	{{ .Decl }}
+{{- end }}
+{{- range .ChanStmts }}
+	{{ . }}
+{{- end }}
+	{{ if .PrintsResults }}fmt.Println({{ .Call }}){{ else }}{{ .Call }}{{ end }}
+	// Output:
+`))
+
+// buildExampleFunc builds an example function which calls target.
+func buildExampleFunc(name string, t *target) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := exampleBodyTmpl.Execute(&buf, t); err != nil {
+		return nil, err
+	}
+
+	return formatFuncDecl(NewExampleFuncDecl(name), buf.String())
+}
+
+// exampleKind generates example functions for exported functions
+// and methods.
+var exampleKind = &funcKind{
+	name:       "Example",
+	funcTmpl:   defaultExpectExampleFuncTmpl,
+	methodTmpl: defaultExpectExampleFuncMethodTmpl,
+	build:      buildExampleFunc,
+
+	// Example of method on unexported type is not shown in godoc.
+	accept: func(t *target) bool {
+		return !isUnExported(t.RecvName)
+	},
+}
@@ -0,0 +1,53 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestBuildExampleFunc(t *testing.T) {
+	src := `package p
+
+func Sum(a, b int) int { return a + b }
+
+func Reset() {}
+`
+	goFile, err := parse("p.go", strings.NewReader(src))
+	if err != nil {
+		t.Fatalf("parse failed: %s", err)
+	}
+
+	cases := []struct {
+		fun      *Func
+		expected string
+	}{
+		{
+			fun: goFile.Funcs[0],
+			expected: `func ExampleSum() {
+	var a int
+	var b int
+	fmt.Println(Sum(a, b))
+	// Output:
+}`,
+		},
+
+		{
+			fun: goFile.Funcs[1],
+			expected: `func ExampleReset() {
+	Reset()
+	// Output:
+}`,
+		},
+	}
+
+	for _, tc := range cases {
+		res, err := buildExampleFunc("Example"+tc.fun.Name, funcTarget(tc.fun))
+		if err != nil {
+			t.Fatalf("buildExampleFunc failed: %s", err)
+		}
+
+		if string(res) != tc.expected {
+			t.Errorf("expected %q to eq %q", res, tc.expected)
+		}
+	}
+}
@@ -0,0 +1,108 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"text/template"
+)
+
+var (
+	defaultExpectFuzzFuncTmpl       = "Fuzz{{ title .Name }}"
+	defaultExpectFuzzFuncMethodTmpl = "Fuzz{{ title .RecvName }}_{{ title .Name }}"
+)
+
+// fuzzSeeds are seed corpus values of each fuzzable type: the zero
+// value and a representative non-zero value. Values are converted
+// explicitly since f.Add requires exactly the same types as f.Fuzz.
+var fuzzSeeds = map[string][2]string{
+	"string":  {`""`, `"gotests"`},
+	"[]byte":  {`[]byte("")`, `[]byte("gotests")`},
+	"bool":    {`false`, `true`},
+	"int":     {`int(0)`, `int(1)`},
+	"int8":    {`int8(0)`, `int8(1)`},
+	"int16":   {`int16(0)`, `int16(1)`},
+	"int32":   {`int32(0)`, `int32(1)`},
+	"int64":   {`int64(0)`, `int64(1)`},
+	"uint":    {`uint(0)`, `uint(1)`},
+	"uint8":   {`uint8(0)`, `uint8(1)`},
+	"uint16":  {`uint16(0)`, `uint16(1)`},
+	"uint32":  {`uint32(0)`, `uint32(1)`},
+	"uint64":  {`uint64(0)`, `uint64(1)`},
+	"byte":    {`byte(0)`, `byte(1)`},
+	"rune":    {`rune(0)`, `rune('a')`},
+	"float32": {`float32(0)`, `float32(1.5)`},
+	"float64": {`float64(0)`, `float64(1.5)`},
+}
+
+// isFuzzable returns true if target is a function whose params
+// are all types which f.Fuzz accepts.
+func isFuzzable(t *target) bool {
+	if t.IsMethod() || len(t.Signature.Params) == 0 {
+		return false
+	}
+
+	for _, param := range t.Signature.Params {
+		if _, ok := fuzzSeeds[param.TypeString()]; !ok {
+			return false
+		}
+	}
+
+	return true
+}
+
+// fuzzBodyTmpl is the body of generated fuzz test. The fuzz target
+// only calls the function: go test reports the input if it panics.
+var fuzzBodyTmpl = template.Must(template.New("fuzz").Parse(`
+{{- range .Seeds }}
+	f.Add({{ . }})
+{{- end }}
+	f.Fuzz(func(t *testing.T, {{ .Params }}) {
+		{{ .Target.Call }}
+	})
+`))
+
+// buildFuzzFunc builds a fuzz test which calls target.
+func buildFuzzFunc(name string, t *target) ([]byte, error) {
+	t.reserve("f", "t")
+
+	var zeros, samples, params []string
+	for i, param := range t.Signature.Params {
+		seeds := fuzzSeeds[param.TypeString()]
+		zeros = append(zeros, seeds[0])
+		samples = append(samples, seeds[1])
+		params = append(params, fmt.Sprintf("%s %s", t.ParamVars[i], param.TypeString()))
+	}
+
+	data := struct {
+		Target *target
+		Seeds  []string
+		Params string
+	}{
+		Target: t,
+		Seeds: []string{
+			joinArgs(zeros),
+			joinArgs(samples),
+		},
+		Params: joinArgs(params),
+	}
+
+	var buf bytes.Buffer
+	if err := fuzzBodyTmpl.Execute(&buf, data); err != nil {
+		return nil, err
+	}
+
+	return formatFuncDecl(NewFuzzFuncDecl(name), buf.String())
+}
+
+// fuzzKind returns funcKind which generates fuzz tests for
+// functions with fuzzable params.
+func fuzzKind(includeUnexported bool) *funcKind {
+	return &funcKind{
+		name:              "Fuzz",
+		funcTmpl:          defaultExpectFuzzFuncTmpl,
+		methodTmpl:        defaultExpectFuzzFuncMethodTmpl,
+		build:             buildFuzzFunc,
+		includeUnexported: includeUnexported,
+		accept:            isFuzzable,
+	}
+}
@@ -0,0 +1,56 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestBuildFuzzFunc(t *testing.T) {
+	src := `package p
+
+func Parse(s string, t []byte, n float64) error { return nil }
+`
+	goFile, err := parse("p.go", strings.NewReader(src))
+	if err != nil {
+		t.Fatalf("parse failed: %s", err)
+	}
+
+	res, err := buildFuzzFunc("FuzzParse", funcTarget(goFile.Funcs[0]))
+	if err != nil {
+		t.Fatalf("buildFuzzFunc failed: %s", err)
+	}
+
+	expected := `func FuzzParse(f *testing.F) {
+	f.Add("", []byte(""), float64(0))
+	f.Add("gotests", []byte("gotests"), float64(1.5))
+	f.Fuzz(func(t *testing.T, s string, arg1 []byte, n float64) {
+		Parse(s, arg1, n)
+	})
+}`
+	if string(res) != expected {
+		t.Errorf("expected %q to eq %q", res, expected)
+	}
+}
+
+func TestIsFuzzable(t *testing.T) {
+	src := `package p
+
+type ID string
+
+func A(s string, n int) {}
+func B(id ID) {}
+func C() {}
+func D(b []byte, r rune) {}
+`
+	goFile, err := parse("p.go", strings.NewReader(src))
+	if err != nil {
+		t.Fatalf("parse failed: %s", err)
+	}
+
+	expected := []bool{true, false, false, true}
+	for i, fun := range goFile.Funcs {
+		if res := isFuzzable(funcTarget(fun)); res != expected[i] {
+			t.Errorf("%s: expected %t to eq %t", fun.Name, res, expected[i])
+		}
+	}
+}
@@ -0,0 +1,130 @@
+package main
+
+import (
+	"fmt"
+	"path/filepath"
+)
+
+// funcKind is a kind of testing functions (e.g., examples or
+// benchmarks) which are generated in addition to tests.
+type funcKind struct {
+	// name is used for messages.
+	name string
+
+	// funcTmpl and methodTmpl are templates of expected
+	// function names like ExpectTestFuncTmpl.
+	funcTmpl   string
+	methodTmpl string
+
+	build declBuilder
+
+	// includeUnexported includes unexported functions/methods.
+	includeUnexported bool
+
+	// accept reports whether the kind of function is generated
+	// for the target. If nil, all targets are accepted.
+	accept func(t *target) bool
+}
+
+// funcKinds returns the kinds of testing functions enabled by opts
+// (-examples, -bench and -fuzz).
+func (opts *generateOpts) funcKinds(goVersion string) []*funcKind {
+	var kinds []*funcKind
+	if opts.examples {
+		kinds = append(kinds, exampleKind)
+	}
+	if opts.bench {
+		kinds = append(kinds, benchmarkKind(goVersion, opts.diffOpts.IncludeUnexported))
+	}
+	if opts.fuzz {
+		kinds = append(kinds, fuzzKind(opts.diffOpts.IncludeUnexported))
+	}
+	return kinds
+}
+
+// addKindFuncs adds testing functions of the kind for functions and
+// methods in goFile which don't have them yet (see diffKindFuncs).
+func addKindFuncs(goFile, goTestFile *GoFile, testPath string, k *funcKind, opts *generateOpts) error {
+	kindOpts := k.diffOpts(opts)
+
+	srcFuncs, err := goFile.expectTestFuncs(kindOpts)
+	if err != nil {
+		return err
+	}
+	p := newPlacer(opts.order, goTestFile, srcFuncs)
+
+	funcs, methods, err := diffKindFuncs(goFile, goTestFile, testPath, k, opts)
+	if err != nil {
+		return err
+	}
+	Debugf("Diff %s Funcs: %#v", k.name, funcs)
+	Debugf("Diff %s Methods: %#v", k.name, methods)
+
+	if err := goTestFile.addFuncTestFuncs(funcs, k.funcTmpl, k.build, p); err != nil {
+		return err
+	}
+
+	if err := goTestFile.addMethodTestFuncs(methods, k.methodTmpl, k.build, p); err != nil {
+		return err
+	}
+
+	p.place()
+
+	return nil
+}
+
+// diffOpts returns diffOpts to find the functions and methods which
+// don't have the kind of testing functions.
+func (k *funcKind) diffOpts(opts *generateOpts) *diffOpts {
+	return &diffOpts{
+		Mode:                     opts.diffOpts.Mode,
+		IncludeUnexported:        k.includeUnexported,
+		ExpectTestFuncTmpl:       k.funcTmpl,
+		ExpectTestFuncMethodTmpl: k.methodTmpl,
+	}
+}
+
+// diffKindFuncs returns the functions and methods in goFile (which the
+// kind accepts) which don't have testing functions of the kind yet in
+// any test file of the package (they are often written in a separated
+// file like example_test.go or bench_test.go).
+func diffKindFuncs(goFile, goTestFile *GoFile, testPath string, k *funcKind, opts *generateOpts) ([]*Func, []*Method, error) {
+	kindOpts := k.diffOpts(opts)
+
+	testFiles, err := parseTestFiles(filepath.Dir(testPath))
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to parse test files: %w", err)
+	}
+	pkgTestFile := &GoFile{
+		Funcs: goTestFile.Funcs,
+	}
+	for _, testFile := range testFiles {
+		pkgTestFile.Funcs = append(pkgTestFile.Funcs, testFile.Funcs...)
+	}
+
+	diffFuncs, err := goFile.diffFuncs(pkgTestFile, kindOpts)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var funcs []*Func
+	for _, fun := range diffFuncs {
+		if k.accept == nil || k.accept(funcTarget(fun)) {
+			funcs = append(funcs, fun)
+		}
+	}
+
+	diffMethods, err := goFile.diffMethods(pkgTestFile, kindOpts)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var methods []*Method
+	for _, method := range diffMethods {
+		if k.accept == nil || k.accept(methodTarget(method)) {
+			methods = append(methods, method)
+		}
+	}
+
+	return funcs, methods, nil
+}
@@ -0,0 +1,194 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"go/ast"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// lineRange is a range of lines (1-based, inclusive).
+type lineRange struct {
+	Start, End int
+}
+
+// gitChanges are the files changed in git (see -since and -staged) keyed
+// by their absolute paths (with symlinks evaluated), with their changed
+// lines in the working tree (or the index with -staged). Lines are read
+// only with -changed-funcs.
+type gitChanges map[string][]lineRange
+
+// lines returns the changed lines of the file of path, and false if
+// the file is not changed.
+func (c gitChanges) lines(path string) ([]lineRange, bool) {
+	path, err := filepath.Abs(path)
+	if err != nil {
+		return nil, false
+	}
+	if resolved, err := filepath.EvalSymlinks(path); err == nil {
+		path = resolved
+	}
+
+	ranges, ok := c[path]
+	return ranges, ok
+}
+
+// gitDiffArgs returns the args of `git diff` with options to compare
+// the working tree with since, or the index with HEAD (or since) if
+// staged is true.
+func gitDiffArgs(since string, staged bool, options ...string) []string {
+	args := append([]string{"diff", "--no-color", "--no-ext-diff"}, options...)
+	if staged {
+		args = append(args, "--cached")
+	}
+	if since != "" {
+		args = append(args, since)
+	}
+	return append(args, "--")
+}
+
+// readGitChanges returns the changed (but not deleted) files of
+// `git diff --name-only` run in the current directory. The changed lines
+// of the files are read from `git diff -U0` if lines is true.
+func readGitChanges(since string, staged, lines bool) (gitChanges, error) {
+	root, err := git("rev-parse", "--show-toplevel")
+	if err != nil {
+		return nil, err
+	}
+	root = bytes.TrimSpace(root)
+	if resolved, err := filepath.EvalSymlinks(string(root)); err == nil {
+		root = []byte(resolved)
+	}
+
+	names, err := git(gitDiffArgs(since, staged, "--name-only", "-z", "--diff-filter=d")...)
+	if err != nil {
+		return nil, err
+	}
+
+	changes := gitChanges{}
+	for _, name := range strings.Split(string(names), "\x00") {
+		if name != "" {
+			changes[filepath.Join(string(root), filepath.FromSlash(name))] = nil
+		}
+	}
+
+	if !lines || len(changes) == 0 {
+		return changes, nil
+	}
+
+	diff, err := git(gitDiffArgs(since, staged, "-U0", "--diff-filter=d", "--no-prefix")...)
+	if err != nil {
+		return nil, err
+	}
+
+	for name, ranges := range parseHunks(diff) {
+		path := filepath.Join(string(root), filepath.FromSlash(name))
+		if _, ok := changes[path]; ok {
+			changes[path] = ranges
+		}
+	}
+	return changes, nil
+}
+
+// filterChanged removes the functions and methods of goFile whose lines
+// are not changed if opts.changedFuncs is true.
+func (opts *generateOpts) filterChanged(srcPath string, goFile *GoFile) {
+	if opts.changedFuncs {
+		lines, _ := opts.changes.lines(srcPath)
+		goFile.filterTargets(goFile.targetsInLines(lines))
+	}
+}
+
+// git runs git with args and returns its output.
+func git(args ...string) ([]byte, error) {
+	var stderr bytes.Buffer
+	cmd := exec.Command("git", args...)
+	cmd.Stderr = &stderr
+
+	out, err := cmd.Output()
+	if err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return nil, fmt.Errorf("git %s: %s", args[0], msg)
+		}
+		return nil, fmt.Errorf("git %s: %s", args[0], err)
+	}
+	return out, nil
+}
+
+// parseHunks returns the changed lines of the new files in diff
+// (`git diff -U0 --no-prefix`) keyed by their names. Lines deleted
+// after a line are a change of the line.
+func parseHunks(diff []byte) map[string][]lineRange {
+	hunks := map[string][]lineRange{}
+
+	var name string
+	scanner := bufio.NewScanner(bytes.NewReader(diff))
+	for scanner.Scan() {
+		line := scanner.Text()
+		switch {
+		case strings.HasPrefix(line, "+++ "):
+			name = strings.TrimPrefix(line, "+++ ")
+
+		case strings.HasPrefix(line, "@@ ") && name != "":
+			// @@ -start[,count] +start[,count] @@
+			fields := strings.Fields(line)
+			if len(fields) < 3 || !strings.HasPrefix(fields[2], "+") {
+				continue
+			}
+
+			start, count := fields[2][1:], "1"
+			if i := strings.Index(start, ","); i >= 0 {
+				start, count = start[:i], start[i+1:]
+			}
+
+			s, err1 := strconv.Atoi(start)
+			n, err2 := strconv.Atoi(count)
+			if err1 != nil || err2 != nil {
+				continue
+			}
+
+			r := lineRange{Start: s, End: s + n - 1}
+			if n == 0 {
+				r.End = s
+			}
+			hunks[name] = append(hunks[name], r)
+		}
+	}
+	return hunks
+}
+
+// targetsInLines returns the names of the functions and methods
+// (e.g., `Add` or `User.Add`) whose declarations (with their doc
+// comments) overlap ranges.
+func (gf *GoFile) targetsInLines(ranges []lineRange) []string {
+	file := gf.FSet.File(gf.AstFile.Pos())
+	changed := func(decl *ast.FuncDecl) bool {
+		start, end := gf.declRange(decl)
+		startLine, endLine := file.Line(file.Pos(start)), file.Line(file.Pos(end))
+		for _, r := range ranges {
+			if r.Start <= endLine && startLine <= r.End {
+				return true
+			}
+		}
+		return false
+	}
+
+	names := []string{}
+	for _, fun := range gf.Funcs {
+		if changed(fun.Decl) {
+			names = append(names, fun.Name)
+		}
+	}
+
+	for _, method := range gf.Methods {
+		if changed(method.Decl) {
+			names = append(names, method.RecvName+"."+method.Name)
+		}
+	}
+
+	return names
+}
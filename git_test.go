@@ -0,0 +1,82 @@
+package main
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestGitDiffArgs(t *testing.T) {
+	cases := []struct {
+		since    string
+		staged   bool
+		expected []string
+	}{
+		{"main", false, []string{"diff", "--no-color", "--no-ext-diff", "--name-only", "main", "--"}},
+		{"", true, []string{"diff", "--no-color", "--no-ext-diff", "--name-only", "--cached", "--"}},
+		{"main", true, []string{"diff", "--no-color", "--no-ext-diff", "--name-only", "--cached", "main", "--"}},
+	}
+
+	for _, c := range cases {
+		if args := gitDiffArgs(c.since, c.staged, "--name-only"); !reflect.DeepEqual(args, c.expected) {
+			t.Errorf("expected %q to eq %q", args, c.expected)
+		}
+	}
+}
+
+func TestParseHunks(t *testing.T) {
+	diff := `diff --git a.go a.go
+index 1111111..2222222 100644
+--- a.go
++++ a.go
+@@ -3 +3 @@ package a
+-func Add() {}
++func Add() int { return 0 }
+@@ -10,2 +9,0 @@ func Sub() {
+-	a := 1
+-	_ = a
+@@ -20,0 +20,3 @@ func Mul() {
++	b := 1
++	_ = b
++	return
+diff --git sub/b.go sub/b.go
+--- sub/b.go
++++ sub/b.go
+@@ -1,0 +2,2 @@
++// b
++
+`
+	expected := map[string][]lineRange{
+		"a.go":     {{3, 3}, {9, 9}, {20, 22}},
+		"sub/b.go": {{2, 3}},
+	}
+
+	if hunks := parseHunks([]byte(diff)); !reflect.DeepEqual(hunks, expected) {
+		t.Errorf("expected %v to eq %v", hunks, expected)
+	}
+}
+
+func TestGoFile_targetsInLines(t *testing.T) {
+	src := "package p\n\ntype User struct{}\n\n// Add adds.\nfunc (u *User) Add() {\n}\n\nfunc Sub() {}\n\nfunc Mul() {}\n"
+	goFile, err := parse("p.go", strings.NewReader(src))
+	if err != nil {
+		t.Fatalf("parse failed: %s", err)
+	}
+
+	cases := []struct {
+		ranges   []lineRange
+		expected []string
+	}{
+		{nil, []string{}},
+		{[]lineRange{{1, 3}}, []string{}},
+		{[]lineRange{{5, 5}}, []string{"User.Add"}},
+		{[]lineRange{{7, 9}}, []string{"Sub", "User.Add"}},
+		{[]lineRange{{9, 9}, {11, 11}}, []string{"Sub", "Mul"}},
+	}
+
+	for _, c := range cases {
+		if names := goFile.targetsInLines(c.ranges); !reflect.DeepEqual(names, c.expected) {
+			t.Errorf("%v: expected %q to eq %q", c.ranges, names, c.expected)
+		}
+	}
+}
@@ -4,10 +4,10 @@ import (
 	"bytes"
 	"fmt"
 	"go/ast"
-	"go/printer"
 	"go/token"
 	"path/filepath"
 	"regexp"
+	"sort"
 	"strings"
 	"text/template"
 
@@ -33,6 +33,16 @@ const (
 	Strict Mode = iota
 )
 
+// String returns the name of the mode.
+func (m Mode) String() string {
+	switch m {
+	case Strict:
+		return "strict"
+	default:
+		return fmt.Sprintf("Mode(%d)", int(m))
+	}
+}
+
 type diffOpts struct {
 	Mode Mode
 
@@ -51,16 +61,56 @@ type GoFile struct {
 	PackageName string
 	FileName    string
 	SrcBytes    []byte
-	Funcs       []string
+	Funcs       []*Func
 	Methods     []*Method
+	Types       []*Type
 
 	FSet    *token.FileSet
 	AstFile *ast.File
+
+	// edits are text to be inserted into SrcBytes by Generate.
+	edits []*edit
+
+	// imports are imports which Generate adds if added declarations
+	// use them. They take priority over the ones goimports finds.
+	imports []*ast.ImportSpec
+
+	// removed are declarations replaced or deleted by edits
+	// (see removeDecl).
+	removed map[*ast.FuncDecl]bool
 }
 
+// Func is a function declared in .go file.
+type Func struct {
+	Name      string
+	Decl      *ast.FuncDecl
+	Signature *Signature
+
+	// Pkg is the package name qualifying the function in external
+	// test package (see qualify). It's empty otherwise.
+	Pkg string
+}
+
+// Type is a type declared at top level of .go file.
+type Type struct {
+	Name string
+	Spec *ast.TypeSpec
+}
+
+// Method is a method declared in .go file.
 type Method struct {
-	RecvName string
-	Name     string
+	RecvName  string
+	Name      string
+	Decl      *ast.FuncDecl
+	Signature *Signature
+
+	// RecvTypeArgs are type arguments to instantiate receiver
+	// of generic type.
+	RecvTypeArgs []string
+
+	// Pkg is the package name qualifying the receiver type in external
+	// test package (see qualify). It's empty otherwise.
+	Pkg string
 }
 
 func NewGoFile(filename, pkgName string) (*GoFile, error) {
@@ -92,108 +142,358 @@ func (o *diffOpts) init() {
 	}
 }
 
+// Generate returns the source of the file with added declarations.
+// Existing source is kept as it is (including comments and formatting)
+// and new declarations and imports are inserted into it.
 func (gf *GoFile) Generate() ([]byte, error) {
-	var buf bytes.Buffer
-	if err := printer.Fprint(&buf, gf.FSet, gf.AstFile); err != nil {
+	src := gf.SrcBytes
+	if len(src) == 0 {
+		// New file does not have any source yet.
+		src = []byte(fmt.Sprintf("package %s\n", gf.PackageName))
+	}
+
+	if len(gf.edits) == 0 {
+		return src, nil
+	}
+
+	edits, err := gf.allEdits()
+	if err != nil {
 		return nil, err
 	}
 
-	return imports.Process(gf.FileName, buf.Bytes(), nil)
+	res := applyEdits(src, edits)
+	if gf.formatsWhole() {
+		return imports.Process(gf.FileName, res, nil)
+	}
+
+	return res, nil
 }
 
-func (gf *GoFile) addFuncTestFuncs(funcs []string, funcTmpl string) error {
-	for _, fun := range funcs {
+// formatsWhole returns true if Generate formats the whole file:
+// new file has no hand-written code, so it's safe to format (and
+// group imports of) the whole file.
+func (gf *GoFile) formatsWhole() bool {
+	return len(gf.SrcBytes) == 0
+}
 
-		tmpl, err := template.New("testFunc").Funcs(funcMap).Parse(funcTmpl)
+// TextEdits returns the edits which change SrcBytes to the result
+// of Generate (e.g., for editors). Edits are sorted by offsets which
+// are resolved in SrcBytes. If Generate formats the whole file,
+// it returns an edit replacing the whole source.
+func (gf *GoFile) TextEdits() ([]*edit, error) {
+	if len(gf.edits) == 0 {
+		return nil, nil
+	}
+
+	if gf.formatsWhole() {
+		res, err := gf.Generate()
 		if err != nil {
-			return err
+			return nil, err
+		}
+		return []*edit{{Offset: 0, End: len(gf.SrcBytes), Text: res}}, nil
+	}
+
+	edits, err := gf.allEdits()
+	if err != nil {
+		return nil, err
+	}
+
+	resolved := make([]*edit, 0, len(edits))
+	for _, e := range edits {
+		offset := offsetOf(gf.SrcBytes, e)
+		end := offset
+		if e.End > offset {
+			end = e.End
 		}
+		resolved = append(resolved, &edit{Offset: offset, End: end, Text: e.Text})
+	}
+	sort.SliceStable(resolved, func(i, j int) bool {
+		return resolved[i].Offset < resolved[j].Offset
+	})
 
-		tmplData := struct {
-			Name string
-		}{
-			Name: fun,
+	return resolved, nil
+}
+
+// allEdits returns edits with the edit adding missing imports and the
+// edits deleting imports used only by removed declarations.
+func (gf *GoFile) allEdits() ([]*edit, error) {
+	var decls bytes.Buffer
+	for _, e := range gf.edits {
+		// Replacements (e.g., renamed identifiers) are not declarations.
+		if e.End > 0 {
+			continue
 		}
+		decls.Write(e.Text)
+	}
 
-		var buf bytes.Buffer
-		if err := tmpl.Execute(&buf, tmplData); err != nil {
+	specs, err := missingImports(gf.FileName, gf.AstFile, gf.imports, decls.Bytes())
+	if err != nil {
+		return nil, err
+	}
+
+	var unused map[*ast.ImportSpec]bool
+	if len(gf.removed) > 0 {
+		unused, err = unusedImports(gf.FileName, gf.AstFile, applyEdits(gf.SrcBytes, gf.edits))
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	edits := gf.edits
+	if len(unused) > 0 {
+		edits = append(deleteImportEdits(gf.FSet, gf.SrcBytes, gf.AstFile, unused, len(specs) > 0), edits...)
+	}
+	if len(specs) > 0 {
+		edits = append([]*edit{importEdit(gf.FSet, gf.AstFile, specs, unused)}, edits...)
+	}
+
+	return edits, nil
+}
+
+// targetAt returns the name of the function (e.g., `Add`) or method
+// (e.g., `User.Add`) declared at offset. It returns empty string if
+// there is no such declaration.
+func (gf *GoFile) targetAt(offset int) string {
+	contains := func(pos, end int) bool {
+		return pos <= offset && offset <= end
+	}
+
+	for _, fun := range gf.Funcs {
+		start, end := gf.declRange(fun.Decl)
+		if contains(start, end) {
+			return fun.Name
+		}
+	}
+
+	for _, method := range gf.Methods {
+		start, end := gf.declRange(method.Decl)
+		if contains(start, end) {
+			return method.RecvName + "." + method.Name
+		}
+	}
+
+	return ""
+}
+
+// targetAtLine returns the name of the function or method declared
+// at line (1-based) like targetAt.
+func (gf *GoFile) targetAtLine(line int) string {
+	file := gf.FSet.File(gf.AstFile.Pos())
+	if line < 1 || line > file.LineCount() {
+		return ""
+	}
+	return gf.targetAt(file.Offset(file.LineStart(line)))
+}
+
+// filterTargets removes functions and methods whose names (e.g., `Add`
+// or `User.Add`) are not in names.
+func (gf *GoFile) filterTargets(names []string) {
+	var funcs []*Func
+	for _, fun := range gf.Funcs {
+		if contains(names, fun.Name) {
+			funcs = append(funcs, fun)
+		}
+	}
+	gf.Funcs = funcs
+
+	var methods []*Method
+	for _, method := range gf.Methods {
+		if contains(names, method.RecvName+"."+method.Name) {
+			methods = append(methods, method)
+		}
+	}
+	gf.Methods = methods
+}
+
+// isExternalTest returns true if pkgName is the name of external test
+// package (e.g., `p_test`).
+func isExternalTest(pkgName string) bool {
+	return strings.HasSuffix(pkgName, "_test")
+}
+
+// qualifiedNames returns the qualified identifiers (e.g., `p.User`) of
+// the exported types and functions declared in pkgFiles of pkgName package
+// keyed by their names. Unexported ones can't be used in external test
+// package anyway.
+func qualifiedNames(pkgName string, pkgFiles []*GoFile) map[string]ast.Expr {
+	names := make(map[string]ast.Expr)
+	add := func(name string) {
+		if !isUnExported(name) {
+			names[name] = &ast.SelectorExpr{X: ast.NewIdent(pkgName), Sel: ast.NewIdent(name)}
+		}
+	}
+
+	for _, goFile := range pkgFiles {
+		for _, typ := range goFile.Types {
+			add(typ.Name)
+		}
+		for _, fun := range goFile.Funcs {
+			add(fun.Name)
+		}
+	}
+	return names
+}
+
+// qualify qualifies the identifiers of the package in the signatures of
+// functions and methods with names (see qualifiedNames), and functions
+// and receiver types with the package name so that they are used in
+// external test package.
+func (gf *GoFile) qualify(names map[string]ast.Expr) {
+	for _, fun := range gf.Funcs {
+		fun.Pkg = gf.PackageName
+		fun.Signature.qualify(names)
+	}
+
+	for _, method := range gf.Methods {
+		method.Pkg = gf.PackageName
+		method.Signature.qualify(names)
+		for i, arg := range method.RecvTypeArgs {
+			method.RecvTypeArgs[i] = substituteString(arg, names)
+		}
+	}
+}
+
+// addImport registers the import of path (with name if it's renamed)
+// which added declarations may use.
+func (gf *GoFile) addImport(name, path string) {
+	gf.imports = append(gf.imports, newImportSpec(name, path))
+}
+
+// appendDecl adds the given declaration source to the end of the file.
+func (gf *GoFile) appendDecl(text []byte) {
+	var buf bytes.Buffer
+	if len(gf.SrcBytes) > 0 && !bytes.HasSuffix(gf.SrcBytes, []byte("\n")) {
+		buf.WriteString("\n")
+	}
+	buf.WriteString("\n")
+	buf.Write(text)
+	buf.WriteString("\n")
+
+	gf.edits = append(gf.edits, &edit{
+		Offset: endOfFile,
+		Text:   buf.Bytes(),
+	})
+}
+
+// declBuilder builds the source of testing function declaration
+// with the given name which exercises target.
+type declBuilder func(name string, t *target) ([]byte, error)
+
+func (gf *GoFile) addFuncTestFuncs(funcs []*Func, funcTmpl string, build declBuilder, p *placer) error {
+	for _, fun := range funcs {
+		name, err := execFuncTmpl(funcTmpl, fun)
+		if err != nil {
 			return err
 		}
 
-		testFunDecl := NewTestFuncDecl(buf.String())
-		gf.AstFile.Decls = append(gf.AstFile.Decls, testFunDecl)
+		text, err := build(name, funcTarget(fun))
+		if err != nil {
+			return err
+		}
+
+		p.add(name, fun.Decl.Pos(), text)
 	}
 
 	return nil
 }
 
-func (gf *GoFile) addMethodTestFuncs(methods []*Method, funcTmpl string) error {
+func (gf *GoFile) addMethodTestFuncs(methods []*Method, funcTmpl string, build declBuilder, p *placer) error {
 	for _, method := range methods {
-
-		tmpl, err := template.New("testFunc").Funcs(funcMap).Parse(funcTmpl)
+		name, err := execFuncTmpl(funcTmpl, method)
 		if err != nil {
 			return err
 		}
 
-		var buf bytes.Buffer
-		if err := tmpl.Execute(&buf, method); err != nil {
+		text, err := build(name, methodTarget(method))
+		if err != nil {
 			return err
 		}
 
-		testFunDecl := NewTestFuncDecl(buf.String())
-		gf.AstFile.Decls = append(gf.AstFile.Decls, testFunDecl)
+		p.add(name, method.Decl.Pos(), text)
 	}
 
 	return nil
 }
 
-func (goFile *GoFile) diffFuncs(goTestFile *GoFile, opts *diffOpts) ([]string, error) {
+// expectTestFuncs returns expected test function names of all functions
+// and methods in goFile in source order. Functions which are not target
+// of generating (e.g., ignored or unexported ones) are included too.
+func (goFile *GoFile) expectTestFuncs(opts *diffOpts) ([]string, error) {
 	opts.init()
 
-	var diff []string
-	for _, fun := range goFile.Funcs {
+	type namedPos struct {
+		name string
+		pos  token.Pos
+	}
 
-		if contains(opts.IgnoreFuncs, fun) {
-			continue
+	var decls []namedPos
+	for _, fun := range goFile.Funcs {
+		name, err := execFuncTmpl(opts.ExpectTestFuncTmpl, fun)
+		if err != nil {
+			return nil, err
 		}
+		decls = append(decls, namedPos{name, fun.Decl.Pos()})
+	}
 
-		if !opts.IncludeUnexported && isUnExported(fun) {
-			continue
+	for _, method := range goFile.Methods {
+		name, err := execFuncTmpl(opts.ExpectTestFuncMethodTmpl, method)
+		if err != nil {
+			return nil, err
 		}
+		decls = append(decls, namedPos{name, method.Decl.Pos()})
+	}
 
-		// exist indicate expected test function is exist on goTestFile
-		// function list.
-		exist := false
+	sort.SliceStable(decls, func(i, j int) bool {
+		return decls[i].pos < decls[j].pos
+	})
 
-		tmpl, err := template.New("testFunc").Funcs(funcMap).Parse(opts.ExpectTestFuncTmpl)
-		if err != nil {
-			return diff, err
+	names := make([]string, 0, len(decls))
+	for _, decl := range decls {
+		names = append(names, decl.name)
+	}
+
+	return names, nil
+}
+
+// execFuncTmpl executes test function name template with data
+// (*Func or *Method) and returns the name.
+func execFuncTmpl(funcTmpl string, data interface{}) (string, error) {
+	tmpl, err := template.New("testFunc").Funcs(funcMap).Parse(funcTmpl)
+	if err != nil {
+		return "", err
+	}
+
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, data); err != nil {
+		return "", err
+	}
+
+	return buf.String(), nil
+}
+
+func (goFile *GoFile) diffFuncs(goTestFile *GoFile, opts *diffOpts) ([]*Func, error) {
+	opts.init()
+
+	var diff []*Func
+	for _, fun := range goFile.Funcs {
+
+		if contains(opts.IgnoreFuncs, fun.Name) {
+			continue
 		}
 
-		tmplData := struct {
-			Name string
-		}{
-			Name: fun,
+		if !opts.IncludeUnexported && isUnExported(fun.Name) {
+			continue
 		}
 
-		var buf bytes.Buffer
-		if err := tmpl.Execute(&buf, tmplData); err != nil {
+		expectTestFun, err := execFuncTmpl(opts.ExpectTestFuncTmpl, fun)
+		if err != nil {
 			return diff, err
 		}
+		Debugf("Expect TestFunc Name: %s", expectTestFun)
 
-		Debugf("Expect TestFunc Name: %s", buf.String())
-		expectTestFun := buf.String()
-
-		for _, testFun := range goTestFile.Funcs {
-			switch mode := opts.Mode; mode {
-			case Strict:
-				if expectTestFun == testFun {
-					exist = true
-				}
-			default:
-				// Should not reach here...
-				return diff, fmt.Errorf("unknown diff mode is provided: %d", mode)
-			}
+		exist, err := goTestFile.hasTestFunc(expectTestFun, opts)
+		if err != nil {
+			return diff, err
 		}
 
 		if !exist {
@@ -214,35 +514,17 @@ func (goFile *GoFile) diffMethods(goTestFile *GoFile, opts *diffOpts) ([]*Method
 			continue
 		}
 
-		// exist indicate expected test function is exist on goTestFile
-		// function list.
-		exist := false
-
-		tmpl, err := template.New("testFunc").Funcs(funcMap).Parse(opts.ExpectTestFuncMethodTmpl)
+		expectTestFun, err := execFuncTmpl(opts.ExpectTestFuncMethodTmpl, method)
 		if err != nil {
 			return diff, err
 		}
+		Debugf("Expect TestFunc Name: %s", expectTestFun)
 
-		var buf bytes.Buffer
-		if err := tmpl.Execute(&buf, method); err != nil {
+		exist, err := goTestFile.hasTestFunc(expectTestFun, opts)
+		if err != nil {
 			return diff, err
 		}
 
-		Debugf("Expect TestFunc Name: %s", buf.String())
-		expectTestFun := buf.String()
-
-		for _, testFun := range goTestFile.Funcs {
-			switch mode := opts.Mode; mode {
-			case Strict:
-				if expectTestFun == testFun {
-					exist = true
-				}
-			default:
-				// Should not reach here...
-				return diff, fmt.Errorf("unknown diff mode is provided: %d", mode)
-			}
-		}
-
 		if !exist {
 			diff = append(diff, method)
 		}
@@ -251,6 +533,24 @@ func (goFile *GoFile) diffMethods(goTestFile *GoFile, opts *diffOpts) ([]*Method
 	return diff, nil
 }
 
+// hasTestFunc returns true if expected test function is exist on
+// goTestFile function list.
+func (goTestFile *GoFile) hasTestFunc(expectTestFun string, opts *diffOpts) (bool, error) {
+	for _, testFun := range goTestFile.Funcs {
+		switch mode := opts.Mode; mode {
+		case Strict:
+			if expectTestFun == testFun.Name {
+				return true, nil
+			}
+		default:
+			// Should not reach here...
+			return false, fmt.Errorf("unknown diff mode is provided: %d", mode)
+		}
+	}
+
+	return false, nil
+}
+
 var reLower = regexp.MustCompile("^[a-z]+")
 
 // isUnexported checks the given function is unxported (
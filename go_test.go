@@ -1,10 +1,188 @@
 package main
 
-import "testing"
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
 
 func TestNewGoFile(t *testing.T) {
 }
 func TestTestFilePath(t *testing.T) {
 }
 func TestGoFile_Generate(t *testing.T) {
+	cases := []struct {
+		src      string
+		expected string
+	}{
+		{
+			src: `package basic
+
+// keep this comment
+import "testing"
+
+func TestExportedA(t *testing.T)   {
+	// and this one
+}
+`,
+			expected: `package basic
+
+// keep this comment
+import "testing"
+
+func TestExportedA(t *testing.T)   {
+	// and this one
+}
+
+func TestExportedB(t *testing.T) {
+}
+`,
+		},
+
+		{
+			src: `package basic
+
+import (
+	"fmt"
+)
+
+var _ = fmt.Sprintf
+`,
+			expected: `package basic
+
+import (
+	"fmt"
+	"testing"
+)
+
+var _ = fmt.Sprintf
+
+func TestExportedB(t *testing.T) {
+}
+`,
+		},
+
+		{
+			src: `package basic`,
+			expected: `package basic
+
+import "testing"
+
+func TestExportedB(t *testing.T) {
+}
+`,
+		},
+	}
+
+	for i, tc := range cases {
+		goFile, err := parse("basic_test.go", strings.NewReader(tc.src))
+		if err != nil {
+			t.Fatalf("#%d parse failed: %s", i, err)
+		}
+
+		text, err := formatDecl(NewTestFuncDecl("TestExportedB"))
+		if err != nil {
+			t.Fatalf("#%d formatDecl failed: %s", i, err)
+		}
+		goFile.appendDecl(text)
+
+		res, err := goFile.Generate()
+		if err != nil {
+			t.Fatalf("#%d Generate failed: %s", i, err)
+		}
+
+		if string(res) != tc.expected {
+			t.Errorf("#%d expected %q to eq %q", i, res, tc.expected)
+		}
+	}
+}
+
+func TestGoFile_Generate_noChange(t *testing.T) {
+	src := []byte("package basic\n\n// comment\nfunc  TestA(t *testing.T) {}\n")
+	goFile, err := parse("basic_test.go", bytes.NewReader(src))
+	if err != nil {
+		t.Fatalf("parse failed: %s", err)
+	}
+
+	res, err := goFile.Generate()
+	if err != nil {
+		t.Fatalf("Generate failed: %s", err)
+	}
+
+	if !bytes.Equal(res, src) {
+		t.Errorf("expected %q to eq %q", res, src)
+	}
+}
+
+func TestGoFile_targetAtLine(t *testing.T) {
+	src := "package p\n\ntype User struct{}\n\n// Add adds.\nfunc (u *User) Add() {\n}\n\nfunc Sub() {}\n"
+	goFile, err := parse("p.go", strings.NewReader(src))
+	if err != nil {
+		t.Fatalf("parse failed: %s", err)
+	}
+
+	cases := []struct {
+		line     int
+		expected string
+	}{
+		{1, ""},
+		{3, ""},
+		{5, "User.Add"},
+		{7, "User.Add"},
+		{9, "Sub"},
+		{100, ""},
+	}
+
+	for _, c := range cases {
+		if name := goFile.targetAtLine(c.line); name != c.expected {
+			t.Errorf("line %d: expected %q to eq %q", c.line, name, c.expected)
+		}
+	}
+}
+
+func TestGoFile_qualify(t *testing.T) {
+	src := `package p
+
+type User struct{}
+
+type Set[T any] struct{}
+
+func Max[T any](a, b T) T { return a }
+
+func NewUser(name string, opts ...Option) (*User, error) { return nil, nil }
+
+func (s *Set[T]) Add(u User) bool { return true }
+`
+	goFile, err := parse("p.go", strings.NewReader(src))
+	if err != nil {
+		t.Fatalf("parse failed: %s", err)
+	}
+	goFile.qualify(qualifiedNames("p", []*GoFile{goFile}))
+
+	newUser := funcTarget(goFile.Funcs[1])
+	if call := newUser.Call(); call != "p.NewUser(name, opts...)" {
+		t.Errorf("expected %q to eq %q", call, "p.NewUser(name, opts...)")
+	}
+
+	// Option is not declared in the package.
+	var types []string
+	for _, field := range append(newUser.Signature.Params, newUser.Signature.Results...) {
+		types = append(types, field.TypeString())
+	}
+	expected := "string, ...Option, *p.User, error"
+	if got := strings.Join(types, ", "); got != expected {
+		t.Errorf("expected %q to eq %q", got, expected)
+	}
+
+	if call := funcTarget(goFile.Funcs[0]).Call(); call != "p.Max[any](a, b)" {
+		t.Errorf("expected %q to eq %q", call, "p.Max[any](a, b)")
+	}
+
+	add := methodTarget(goFile.Methods[0])
+	if add.RecvType != "p.Set[any]" || add.Call() != "s.Add(u)" {
+		t.Errorf("expected %q, %q to eq %q, %q", add.RecvType, add.Call(), "p.Set[any]", "s.Add(u)")
+	}
+	if typ := add.Signature.Params[0].TypeString(); typ != "p.User" {
+		t.Errorf("expected %q to eq %q", typ, "p.User")
+	}
 }
@@ -0,0 +1,112 @@
+package main
+
+import (
+	"go/ast"
+	"go/token"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// goldenTypes are result types which golden-file tests compare
+// with the content of golden files.
+var goldenTypes = []string{"[]byte", "string", "io.Reader"}
+
+// goldenTmpl defines "golden" which compares a result with golden file
+// of the test case. It's shared by all AssertStyles.
+const goldenTmpl = `
+{{- define "golden" }}
+			{{- if eq .Type "io.Reader" }}
+			{{ .Got }}Bytes, err := io.ReadAll({{ .Got }})
+			if err != nil {
+				t.Fatalf("failed to read {{ .Name }}() {{ .Got }}: %v", err)
+			}
+			{{- end }}
+			golden := filepath.Join("testdata", filepath.FromSlash(t.Name())+".golden")
+			if *update {
+				if err := os.MkdirAll(filepath.Dir(golden), 0755); err != nil {
+					t.Fatal(err)
+				}
+				if err := os.WriteFile(golden, {{ .Bytes }}, 0644); err != nil {
+					t.Fatal(err)
+				}
+			}
+			want, err := os.ReadFile(golden)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal({{ .Bytes }}, want) {
+				t.Errorf("{{ .Name }}() {{ .Got }} = %q, want %q", {{ .Bytes }}, want)
+			}
+{{- end }}`
+
+// updateFlagDecl is the declaration of the flag to rewrite golden files
+// (`go test -update`).
+const updateFlagDecl = `var update = flag.Bool("update", false, "update golden files")`
+
+// markGolden marks the result compared with golden files. Target must
+// have exactly one result (other than error) of goldenTypes. It returns
+// false if no result is marked.
+func markGolden(results []*Result) bool {
+	var golden *Result
+	for _, result := range results {
+		if result.IsError {
+			continue
+		}
+		if golden != nil || !contains(goldenTypes, result.Type) {
+			return false
+		}
+		golden = result
+	}
+
+	if golden == nil {
+		return false
+	}
+	golden.Golden = true
+	return true
+}
+
+// goldenPath returns the path of the golden file of the test case
+// relative to the package directory. Spaces in case name are replaced
+// with underscores as t.Run does.
+func goldenPath(testName, caseName string) string {
+	caseName = strings.Replace(caseName, " ", "_", -1)
+	return filepath.Join("testdata", testName, caseName+".golden")
+}
+
+// goldenFiles returns empty golden files at paths (relative to dir).
+// Existing files are kept as they are.
+func goldenFiles(dir string, paths []string) []outputFile {
+	var files []outputFile
+	for _, path := range paths {
+		path = filepath.Join(dir, path)
+		_, err := os.Stat(path)
+		files = append(files, &newFile{
+			path:   path,
+			exists: err == nil,
+		})
+	}
+	return files
+}
+
+// hasVar returns true if any of goFiles declares package-level
+// variable name.
+func hasVar(goFiles []*GoFile, name string) bool {
+	for _, goFile := range goFiles {
+		for _, decl := range goFile.AstFile.Decls {
+			genDecl, ok := decl.(*ast.GenDecl)
+			if !ok || genDecl.Tok != token.VAR {
+				continue
+			}
+
+			for _, spec := range genDecl.Specs {
+				for _, ident := range spec.(*ast.ValueSpec).Names {
+					if ident.Name == name {
+						return true
+					}
+				}
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,129 @@
+package main
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestMarkGolden(t *testing.T) {
+	cases := []struct {
+		src      string
+		expected bool
+	}{
+		{"func F() string", true},
+		{"func F() ([]byte, error)", true},
+		{"func F() io.Reader", true},
+		{"func F() int", false},
+		{"func F() error", false},
+		{"func F() (string, string)", false},
+		{"func F()", false},
+	}
+
+	for _, tc := range cases {
+		goFile, err := parse("p.go", strings.NewReader("package p\n\n"+tc.src+" { panic(0) }\n"))
+		if err != nil {
+			t.Fatalf("parse failed: %s", err)
+		}
+
+		results := funcTarget(goFile.Funcs[0]).Results()
+		if got := markGolden(results); got != tc.expected {
+			t.Errorf("%s: expected %t to eq %t", tc.src, got, tc.expected)
+		}
+	}
+}
+
+func TestGoldenPath(t *testing.T) {
+	expected := filepath.Join("testdata", "TestRender", "with_name.golden")
+	if got := goldenPath("TestRender", "with name"); got != expected {
+		t.Errorf("expected %q to eq %q", got, expected)
+	}
+}
+
+func TestHasVar(t *testing.T) {
+	src := `package p
+
+var (
+	update = flag.Bool("update", false, "")
+)
+`
+	goFile, err := parse("p_test.go", strings.NewReader(src))
+	if err != nil {
+		t.Fatalf("parse failed: %s", err)
+	}
+
+	if !hasVar([]*GoFile{goFile}, "update") {
+		t.Errorf("expected update to be found")
+	}
+	if hasVar([]*GoFile{goFile}, "golden") {
+		t.Errorf("expected golden not to be found")
+	}
+}
+
+func TestTestBuilder_golden(t *testing.T) {
+	src := `package p
+
+func Render(name string) ([]byte, error) { return nil, nil }
+`
+	goFile, err := parse("p.go", strings.NewReader(src))
+	if err != nil {
+		t.Fatalf("parse failed: %s", err)
+	}
+
+	builder := &testBuilder{table: true, golden: true}
+	res, err := builder.build("TestRender", funcTarget(goFile.Funcs[0]))
+	if err != nil {
+		t.Fatalf("build failed: %s", err)
+	}
+
+	expected := `func TestRender(t *testing.T) {
+	type args struct {
+		name string
+	}
+	tests := []struct {
+		name    string
+		args    args
+		wantErr bool
+	}{
+		// TODO: Add test cases.
+		{
+			name: "default",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Render(tt.args.name)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Render() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			golden := filepath.Join("testdata", filepath.FromSlash(t.Name())+".golden")
+			if *update {
+				if err := os.MkdirAll(filepath.Dir(golden), 0755); err != nil {
+					t.Fatal(err)
+				}
+				if err := os.WriteFile(golden, got, 0644); err != nil {
+					t.Fatal(err)
+				}
+			}
+			want, err := os.ReadFile(golden)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(got, want) {
+				t.Errorf("Render() got = %q, want %q", got, want)
+			}
+		})
+	}
+}`
+	if string(res) != expected {
+		t.Errorf("expected %q to eq %q", res, expected)
+	}
+
+	paths := []string{filepath.Join("testdata", "TestRender", "default.golden")}
+	if len(builder.goldenPaths) != 1 || builder.goldenPaths[0] != paths[0] {
+		t.Errorf("expected %q to eq %q", builder.goldenPaths, paths)
+	}
+}
@@ -0,0 +1,114 @@
+package main
+
+import (
+	"bufio"
+	"go/build"
+	"go/version"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// moduleGoVersion returns the Go version declared by `go` directive
+// in go.mod of the module which dir belongs to (e.g., "1.22").
+// It returns empty string if go.mod or the directive is not found.
+func moduleGoVersion(dir string) string {
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return ""
+	}
+
+	for {
+		f, err := os.Open(filepath.Join(dir, "go.mod"))
+		if err == nil {
+			defer f.Close()
+
+			scanner := bufio.NewScanner(f)
+			for scanner.Scan() {
+				fields := strings.Fields(scanner.Text())
+				if len(fields) == 2 && fields[0] == "go" {
+					return fields[1]
+				}
+			}
+			return ""
+		}
+
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return ""
+		}
+		dir = parent
+	}
+}
+
+// moduleFilePath returns the path of the file of path as the coverage
+// profile names it: the module path declared by `module` directive in
+// go.mod of the module which the file belongs to, joined with the path of
+// the file relative to the module root (e.g., "example.com/m/pkg/file.go").
+// It returns empty string if go.mod or the directive is not found.
+func moduleFilePath(path string) string {
+	path, err := filepath.Abs(path)
+	if err != nil {
+		return ""
+	}
+
+	dir := filepath.Dir(path)
+	for {
+		f, err := os.Open(filepath.Join(dir, "go.mod"))
+		if err == nil {
+			defer f.Close()
+
+			rel, err := filepath.Rel(dir, path)
+			if err != nil {
+				return ""
+			}
+
+			scanner := bufio.NewScanner(f)
+			for scanner.Scan() {
+				fields := strings.Fields(scanner.Text())
+				if len(fields) == 2 && fields[0] == "module" {
+					return strings.Trim(fields[1], `"`) + "/" + filepath.ToSlash(rel)
+				}
+			}
+			return ""
+		}
+
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return ""
+		}
+		dir = parent
+	}
+}
+
+// packageImportPath returns the import path of the package of the file:
+// the module file path (see moduleFilePath) of the file without its
+// name, or the path relative to src directory of GOPATH. It returns empty
+// string if it's not found.
+func packageImportPath(filename string) string {
+	if name := moduleFilePath(filename); name != "" {
+		return path.Dir(name)
+	}
+
+	dir, err := filepath.Abs(filepath.Dir(filename))
+	if err != nil {
+		return ""
+	}
+	for _, gopath := range filepath.SplitList(build.Default.GOPATH) {
+		rel, err := filepath.Rel(filepath.Join(gopath, "src"), dir)
+		if err == nil && rel != "." && !strings.HasPrefix(rel, "..") {
+			return filepath.ToSlash(rel)
+		}
+	}
+	return ""
+}
+
+// goVersionAtLeast returns true if Go version v (e.g., "1.22")
+// is equal to or later than min. Unknown version is treated as old one.
+func goVersionAtLeast(v, min string) bool {
+	if v == "" {
+		return false
+	}
+	return version.Compare("go"+v, "go"+min) >= 0
+}
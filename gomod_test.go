@@ -0,0 +1,85 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestModuleGoVersion(t *testing.T) {
+	dir, err := ioutil.TempDir("", Name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	gomod := "module example.com/m\n\ngo 1.21\n"
+	if err := ioutil.WriteFile(filepath.Join(dir, "go.mod"), []byte(gomod), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	sub := filepath.Join(dir, "sub")
+	if err := os.Mkdir(sub, 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	if v := moduleGoVersion(sub); v != "1.21" {
+		t.Errorf("expected %q to eq %q", v, "1.21")
+	}
+}
+
+func TestModuleFilePath(t *testing.T) {
+	dir, err := ioutil.TempDir("", Name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	gomod := "module example.com/m\n\ngo 1.21\n"
+	if err := ioutil.WriteFile(filepath.Join(dir, "go.mod"), []byte(gomod), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := "example.com/m/sub/file.go"
+	if p := moduleFilePath(filepath.Join(dir, "sub", "file.go")); p != expected {
+		t.Errorf("expected %q to eq %q", p, expected)
+	}
+}
+
+func TestPackageImportPath(t *testing.T) {
+	dir, err := ioutil.TempDir("", Name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	gomod := "module example.com/m\n\ngo 1.21\n"
+	if err := ioutil.WriteFile(filepath.Join(dir, "go.mod"), []byte(gomod), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := "example.com/m/sub"
+	if p := packageImportPath(filepath.Join(dir, "sub", "file.go")); p != expected {
+		t.Errorf("expected %q to eq %q", p, expected)
+	}
+}
+
+func TestGoVersionAtLeast(t *testing.T) {
+	cases := []struct {
+		v, min   string
+		expected bool
+	}{
+		{"1.24", "1.24", true},
+		{"1.24.1", "1.24", true},
+		{"1.25", "1.24", true},
+		{"1.9", "1.24", false},
+		{"", "1.24", false},
+	}
+
+	for _, tc := range cases {
+		if res := goVersionAtLeast(tc.v, tc.min); res != tc.expected {
+			t.Errorf("%s >= %s: expected %t to eq %t", tc.v, tc.min, res, tc.expected)
+		}
+	}
+}
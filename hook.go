@@ -0,0 +1,86 @@
+package main
+
+import (
+	"bytes"
+	"go/token"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/template"
+)
+
+// hookMarker marks pre-commit hooks installed by gotests, which are
+// overwritten by the next install.
+const hookMarker = "Installed by 'gotests hook install'."
+
+// installHook writes the git pre-commit hook which runs gotests with
+// -check and -staged, and args (options and PATHs), in the repository
+// of the current directory. It returns the path of the hook. Existing
+// hooks are not overwritten unless they are installed by gotests.
+func installHook(args []string) (string, error) {
+	dir, err := git("rev-parse", "--git-path", "hooks")
+	if err != nil {
+		return "", err
+	}
+
+	path := filepath.Join(string(bytes.TrimSpace(dir)), "pre-commit")
+	if data, err := ioutil.ReadFile(path); err == nil && !bytes.Contains(data, []byte(hookMarker)) {
+		return "", &posError{
+			Pos: token.Position{Filename: path},
+			Msg: "pre-commit hook already exists (not installed by gotests)",
+		}
+	}
+
+	quoted := make([]string, 0, len(args))
+	for _, arg := range args {
+		quoted = append(quoted, shellQuote(arg))
+	}
+
+	var buf bytes.Buffer
+	err = hookTmpl.Execute(&buf, struct {
+		Marker string
+		Args   string
+	}{
+		Marker: hookMarker,
+		Args:   strings.Join(quoted, " "),
+	})
+	if err != nil {
+		return "", err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return "", err
+	}
+	if err := ioutil.WriteFile(path, buf.Bytes(), 0755); err != nil {
+		return "", err
+	}
+	// WriteFile keeps the mode of the existing hook.
+	return path, os.Chmod(path, 0755)
+}
+
+// shellQuote quotes s for sh if it has characters other than safe ones.
+func shellQuote(s string) string {
+	safe := s != "" && strings.IndexFunc(s, func(r rune) bool {
+		return !(r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' ||
+			strings.ContainsRune("-_=./,:@%+", r))
+	}) < 0
+	if safe {
+		return s
+	}
+	return "'" + strings.Replace(s, "'", `'\''`, -1) + "'"
+}
+
+var hookTmpl = template.Must(template.New("hook").Parse(`#!/bin/sh
+# {{ .Marker }}
+#
+# It checks that the functions and methods of the staged Go files have
+# tests. Run 'git commit --no-verify' to commit without checking.
+
+if ! command -v gotests >/dev/null 2>&1; then
+	echo "pre-commit: gotests is not found in PATH" >&2
+	exit 1
+fi
+
+exec gotests -check -staged {{ .Args }}
+`))
@@ -0,0 +1,42 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestShellQuote(t *testing.T) {
+	cases := []struct {
+		s, expected string
+	}{
+		{"-changed-funcs", "-changed-funcs"},
+		{"-rename=User.Add=User.Insert", "-rename=User.Add=User.Insert"},
+		{"./pkg/...", "./pkg/..."},
+		{"", "''"},
+		{"my dir", "'my dir'"},
+		{"it's", `'it'\''s'`},
+		{"$HOME", "'$HOME'"},
+	}
+
+	for _, c := range cases {
+		if got := shellQuote(c.s); got != c.expected {
+			t.Errorf("expected %q to eq %q", got, c.expected)
+		}
+	}
+}
+
+func TestCLI_Run_hook(t *testing.T) {
+	outStream, errStream := new(bytes.Buffer), new(bytes.Buffer)
+	cli := &CLI{outStream: outStream, errStream: errStream}
+	args := strings.Split("./gotests hook uninstall", " ")
+
+	if status := cli.Run(args); status != ExitCodeError {
+		t.Errorf("expected %d to eq %d", status, ExitCodeError)
+	}
+
+	expected := "Invalid arguments: usage: gotests hook install [options] [PATH ...]\n"
+	if errStream.String() != expected {
+		t.Errorf("expected %q to eq %q", errStream.String(), expected)
+	}
+}
@@ -0,0 +1,229 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"go/ast"
+	"go/format"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// candidate is a function or method without test which can be
+// selected in -interactive.
+type candidate struct {
+	// Name is the name of target (e.g., `Add` or `User.Add`).
+	Name string
+
+	// Signature is the declaration without body
+	// (e.g., `func (u *User) Add(name string) error`).
+	Signature string
+
+	Selected bool
+}
+
+// missingTargets returns the functions and methods of the source file
+// whose tests (or the other testing functions enabled by opts, e.g.,
+// benchmarks of -bench) are missing, in the order of the source file.
+func missingTargets(srcPath, testPath string, opts *generateOpts) ([]*candidate, error) {
+	goFile, err := parseFileOverlay(srcPath, opts.overlay)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse go file: %w", err)
+	}
+
+	goTestFile, err := openTestFile(testPath, goFile.PackageName, opts.overlay)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(opts.renames) > 0 {
+		if err := goTestFile.renameTests(opts.renames, opts.diffOpts); err != nil {
+			return nil, fmt.Errorf("failed to rename tests: %s", err)
+		}
+	}
+
+	diffFuncs, err := goFile.diffFuncs(goTestFile, opts.diffOpts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to diff source file and test file: %s", err)
+	}
+
+	diffMethods, err := goFile.diffMethods(goTestFile, opts.diffOpts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to diff source file and test file: %s", err)
+	}
+
+	for _, kind := range opts.funcKinds(moduleGoVersion(filepath.Dir(srcPath))) {
+		funcs, methods, err := diffKindFuncs(goFile, goTestFile, testPath, kind, opts)
+		if err != nil {
+			return nil, fmt.Errorf("failed to diff %s funcs: %s", strings.ToLower(kind.name), err)
+		}
+		diffFuncs = append(diffFuncs, funcs...)
+		diffMethods = append(diffMethods, methods...)
+	}
+
+	var candidates []*candidate
+	for _, fun := range goFile.Funcs {
+		if containsFunc(diffFuncs, fun) {
+			candidates = append(candidates, &candidate{
+				Name:      fun.Name,
+				Signature: goFile.signature(fun.Decl),
+				Selected:  true,
+			})
+		}
+	}
+
+	for _, method := range goFile.Methods {
+		if containsMethod(diffMethods, method) {
+			candidates = append(candidates, &candidate{
+				Name:      method.RecvName + "." + method.Name,
+				Signature: goFile.signature(method.Decl),
+				Selected:  true,
+			})
+		}
+	}
+
+	return candidates, nil
+}
+
+// signature returns decl without doc comment and body.
+func (gf *GoFile) signature(decl *ast.FuncDecl) string {
+	var buf bytes.Buffer
+	err := format.Node(&buf, gf.FSet, &ast.FuncDecl{
+		Recv: decl.Recv,
+		Name: decl.Name,
+		Type: decl.Type,
+	})
+	if err != nil {
+		return decl.Name.Name
+	}
+	return buf.String()
+}
+
+// selectTargets lists candidates of the source file and lets user
+// toggle them, and returns the names of the selected ones. On terminal
+// it prompts until user confirms the selection. Otherwise (e.g., piped
+// input), it reads a line of the numbers to select and does not prompt.
+// Empty line or EOF selects none then.
+func (cli *CLI) selectTargets(srcPath string, candidates []*candidate) ([]string, error) {
+	tty := isTerminal(cli.inStream)
+	if cli.prompt == nil {
+		cli.prompt = bufio.NewReader(cli.inStream)
+	}
+
+	path, err := fmtPath(srcPath)
+	if err != nil {
+		return nil, err
+	}
+
+	for {
+		fmt.Fprintf(cli.errStream, "%s:\n", path)
+		for i, c := range candidates {
+			mark := " "
+			if c.Selected {
+				mark = "x"
+			}
+			fmt.Fprintf(cli.errStream, "  [%s] %2d  %s\n", mark, i+1, c.Signature)
+		}
+
+		if tty {
+			fmt.Fprint(cli.errStream, "Toggle (e.g., 1 3 5-7), a (all), n (none), q (skip), Enter to generate: ")
+		}
+
+		line, err := cli.prompt.ReadString('\n')
+		if err != nil && err != io.EOF {
+			return nil, err
+		}
+		eof := err == io.EOF
+		line = strings.TrimSpace(line)
+
+		switch line {
+		case "":
+			// Without terminal (or at EOF, e.g., /dev/null which looks
+			// like a terminal), nothing is selected unless the numbers
+			// are given (e.g., no stdin in CI).
+			if !tty || eof {
+				return nil, nil
+			}
+			return selectedNames(candidates), nil
+		case "q":
+			return nil, nil
+		case "a", "n":
+			for _, c := range candidates {
+				c.Selected = line == "a"
+			}
+		default:
+			// Without terminal, the line is the numbers to generate.
+			if !tty {
+				for _, c := range candidates {
+					c.Selected = false
+				}
+			}
+			if err := toggle(candidates, line); err != nil {
+				if !tty {
+					return nil, err
+				}
+				fmt.Fprintf(cli.errStream, "%s\n", err)
+				continue
+			}
+		}
+
+		// The selection is read only once without terminal.
+		if !tty || eof {
+			return selectedNames(candidates), nil
+		}
+	}
+}
+
+// toggle toggles candidates of numbers (1-based) and ranges of them
+// separated by spaces or commas (e.g., `1 3 5-7`).
+func toggle(candidates []*candidate, line string) error {
+	fields := strings.FieldsFunc(line, func(r rune) bool {
+		return r == ' ' || r == ','
+	})
+
+	for _, field := range fields {
+		from, to := field, field
+		if i := strings.Index(field, "-"); i > 0 {
+			from, to = field[:i], field[i+1:]
+		}
+
+		start, err1 := strconv.Atoi(from)
+		end, err2 := strconv.Atoi(to)
+		if err1 != nil || err2 != nil || start < 1 || end > len(candidates) || start > end {
+			return fmt.Errorf("invalid selection: %s", field)
+		}
+
+		for i := start; i <= end; i++ {
+			candidates[i-1].Selected = !candidates[i-1].Selected
+		}
+	}
+	return nil
+}
+
+func selectedNames(candidates []*candidate) []string {
+	var names []string
+	for _, c := range candidates {
+		if c.Selected {
+			names = append(names, c.Name)
+		}
+	}
+	return names
+}
+
+// isTerminal returns true if r is a terminal.
+func isTerminal(r io.Reader) bool {
+	f, ok := r.(*os.File)
+	if !ok {
+		return false
+	}
+
+	fi, err := f.Stat()
+	if err != nil {
+		return false
+	}
+	return fi.Mode()&os.ModeCharDevice != 0
+}
@@ -0,0 +1,139 @@
+package main
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestToggle(t *testing.T) {
+	cases := []struct {
+		line     string
+		expected string
+		err      bool
+	}{
+		{"1", "B, C, D", false},
+		{"1 3", "B, D", false},
+		{"2-4", "A", false},
+		{"1,4", "B, C", false},
+		{"5", "", true},
+		{"3-2", "", true},
+		{"x", "", true},
+	}
+
+	for _, c := range cases {
+		candidates := []*candidate{
+			{Name: "A", Selected: true},
+			{Name: "B", Selected: true},
+			{Name: "C", Selected: true},
+			{Name: "D", Selected: true},
+		}
+
+		err := toggle(candidates, c.line)
+		if c.err {
+			if err == nil {
+				t.Errorf("expected error for %q", c.line)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatalf("toggle failed: %s", err)
+		}
+
+		if got := strings.Join(selectedNames(candidates), ", "); got != c.expected {
+			t.Errorf("expected %q to eq %q", got, c.expected)
+		}
+	}
+}
+
+func TestCLI_selectTargets(t *testing.T) {
+	cases := []struct {
+		input    string
+		expected string
+	}{
+		// Without terminal, the line is the numbers to generate.
+		{"1 3\n", "Add, User.Insert"},
+		{"2", "Sub"},
+		{"\n", ""},
+		{"", ""},
+		{"a\n", "Add, Sub, User.Insert"},
+		{"n\n", ""},
+		{"q\n", ""},
+	}
+
+	for _, c := range cases {
+		outStream, errStream := new(bytes.Buffer), new(bytes.Buffer)
+		cli := &CLI{outStream: outStream, errStream: errStream, inStream: strings.NewReader(c.input)}
+
+		candidates := []*candidate{
+			{Name: "Add", Signature: "func Add(a, b int) int", Selected: true},
+			{Name: "Sub", Signature: "func Sub(a, b int) int", Selected: true},
+			{Name: "User.Insert", Signature: "func (u *User) Insert(name string) error", Selected: true},
+		}
+
+		names, err := cli.selectTargets("p.go", candidates)
+		if err != nil {
+			t.Fatalf("selectTargets failed: %s", err)
+		}
+
+		if got := strings.Join(names, ", "); got != c.expected {
+			t.Errorf("expected %q to eq %q", got, c.expected)
+		}
+
+		if !strings.Contains(errStream.String(), "[x]  3  func (u *User) Insert(name string) error") {
+			t.Errorf("expected candidates to be listed: %s", errStream.String())
+		}
+	}
+}
+
+func TestMissingTargets(t *testing.T) {
+	dir, err := ioutil.TempDir("", Name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	src := "package p\n\nfunc Add(a, b int) int { return a + b }\n\nfunc Sub(a, b int) int { return a - b }\n"
+	testSrc := "package p\n\nimport \"testing\"\n\nfunc TestAdd(t *testing.T) {}\n\nfunc TestSub(t *testing.T) {}\n"
+	srcPath := filepath.Join(dir, "p.go")
+	testPath := filepath.Join(dir, "p_test.go")
+	if err := ioutil.WriteFile(srcPath, []byte(src), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(testPath, []byte(testSrc), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	// Benchmarks are written in the other test file.
+	benchSrc := "package p\n\nimport \"testing\"\n\nfunc BenchmarkAdd(b *testing.B) {}\n"
+	if err := ioutil.WriteFile(filepath.Join(dir, "bench_test.go"), []byte(benchSrc), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	cases := []struct {
+		opts     *generateOpts
+		expected string
+	}{
+		{&generateOpts{diffOpts: &diffOpts{}}, ""},
+		{&generateOpts{diffOpts: &diffOpts{}, bench: true}, "Sub"},
+		{&generateOpts{diffOpts: &diffOpts{}, bench: true, fuzz: true}, "Add, Sub"},
+	}
+
+	for _, c := range cases {
+		candidates, err := missingTargets(srcPath, testPath, c.opts)
+		if err != nil {
+			t.Fatalf("missingTargets failed: %s", err)
+		}
+
+		var names []string
+		for _, candidate := range candidates {
+			names = append(names, candidate.Name)
+		}
+		if got := strings.Join(names, ", "); got != c.expected {
+			t.Errorf("expected %q to eq %q", got, c.expected)
+		}
+	}
+}
@@ -0,0 +1,187 @@
+package main
+
+import (
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"os"
+	"path/filepath"
+)
+
+// jsonResult is the result of generating tests of a source file
+// printed by -json. Editors apply its edits to their buffers instead
+// of gotests rewriting the files.
+type jsonResult struct {
+	// Source is the absolute path of the source file.
+	Source string `json:"source"`
+
+	// Files are the files to be changed (e.g., the test file
+	// and the files of fakes).
+	Files []*jsonFile `json:"files"`
+
+	// Errors are the errors of generation.
+	Errors []*jsonError `json:"errors,omitempty"`
+}
+
+// jsonError is an error of generation. Line and Col (1-based) are zero
+// if the error has no position in the file.
+type jsonError struct {
+	Path    string `json:"path"`
+	Line    int    `json:"line,omitempty"`
+	Col     int    `json:"col,omitempty"`
+	Message string `json:"message"`
+}
+
+// jsonFile is the changes of a file.
+type jsonFile struct {
+	// Path is the absolute path of the file.
+	Path string `json:"path"`
+
+	// Create is true if the file does not exist yet.
+	Create bool `json:"create,omitempty"`
+
+	// Edits are sorted by their offsets in the current content and
+	// do not overlap. Apply them from the last one.
+	Edits []*jsonEdit `json:"edits"`
+
+	// Tests are the functions added to the file. Their lines are the
+	// ones in the new content.
+	Tests []*jsonTest `json:"tests,omitempty"`
+
+	// Diff is the unified diff of the file (with -d).
+	Diff string `json:"diff,omitempty"`
+}
+
+// jsonEdit replaces the range [Start, End) of the current content
+// with Text.
+type jsonEdit struct {
+	Start *jsonPos `json:"start"`
+	End   *jsonPos `json:"end"`
+	Text  string   `json:"text"`
+}
+
+// jsonPos is the position in the content. Offset is in bytes (0-based),
+// Line is 1-based and Col is 1-based in bytes like go/token.Position.
+type jsonPos struct {
+	Offset int `json:"offset"`
+	Line   int `json:"line"`
+	Col    int `json:"col"`
+}
+
+// jsonTest is a test function added to the file.
+type jsonTest struct {
+	Name string `json:"name"`
+	Line int    `json:"line"`
+}
+
+// newJSONFile returns the changes of outFile. It returns nil
+// if outFile is not changed.
+func newJSONFile(outFile outputFile, diff bool) (*jsonFile, error) {
+	edits, err := outputEdits(outFile)
+	if err != nil || len(edits) == 0 {
+		return nil, err
+	}
+
+	path, err := filepath.Abs(outFile.Path())
+	if err != nil {
+		return nil, err
+	}
+
+	f := &jsonFile{Path: path}
+	if _, err := os.Stat(path); os.IsNotExist(err) {
+		f.Create = true
+	}
+
+	orig := outFile.Original()
+	for _, e := range edits {
+		f.Edits = append(f.Edits, &jsonEdit{
+			Start: posOf(orig, e.Offset),
+			End:   posOf(orig, e.End),
+			Text:  string(e.Text),
+		})
+	}
+
+	res, err := outFile.Generate()
+	if err != nil {
+		return nil, err
+	}
+
+	if filepath.Ext(path) == ".go" {
+		f.Tests = addedFuncs(orig, res)
+	}
+
+	if diff {
+		data, err := doDiff(orig, res)
+		if err != nil {
+			return nil, err
+		}
+		f.Diff = "diff " + path + "\n" + string(data)
+	}
+
+	return f, nil
+}
+
+// newJSONErrors returns err of generating tests of the source file of
+// path with their positions (see positionedErrors).
+func newJSONErrors(path string, err error) []*jsonError {
+	var errs []*jsonError
+	for _, e := range positionedErrors(path, err) {
+		filename, err := filepath.Abs(e.Pos.Filename)
+		if err != nil {
+			filename = e.Pos.Filename
+		}
+		errs = append(errs, &jsonError{
+			Path:    filename,
+			Line:    e.Pos.Line,
+			Col:     e.Pos.Column,
+			Message: e.Msg,
+		})
+	}
+	return errs
+}
+
+// posOf returns the position of offset in src.
+func posOf(src []byte, offset int) *jsonPos {
+	pos := &jsonPos{Offset: offset, Line: 1, Col: 1}
+	for _, b := range src[:offset] {
+		if b == '\n' {
+			pos.Line++
+			pos.Col = 1
+		} else {
+			pos.Col++
+		}
+	}
+	return pos
+}
+
+// addedFuncs returns the functions declared in res but not in orig.
+// Both must be Go source (orig may be empty).
+func addedFuncs(orig, res []byte) []*jsonTest {
+	names := make(map[string]bool)
+	if f, err := parser.ParseFile(token.NewFileSet(), "", orig, 0); err == nil {
+		for _, decl := range f.Decls {
+			if funcDecl, ok := decl.(*ast.FuncDecl); ok && funcDecl.Recv == nil {
+				names[funcDecl.Name.Name] = true
+			}
+		}
+	}
+
+	fset := token.NewFileSet()
+	f, err := parser.ParseFile(fset, "", res, 0)
+	if err != nil {
+		return nil
+	}
+
+	var tests []*jsonTest
+	for _, decl := range f.Decls {
+		funcDecl, ok := decl.(*ast.FuncDecl)
+		if !ok || funcDecl.Recv != nil || names[funcDecl.Name.Name] {
+			continue
+		}
+		tests = append(tests, &jsonTest{
+			Name: funcDecl.Name.Name,
+			Line: fset.Position(funcDecl.Pos()).Line,
+		})
+	}
+	return tests
+}
@@ -0,0 +1,88 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestNewJSONFile(t *testing.T) {
+	src := "package p\n\nimport \"testing\"\n\nfunc TestAdd(t *testing.T) {}\n"
+	goFile, err := parse("p_test.go", strings.NewReader(src))
+	if err != nil {
+		t.Fatalf("parse failed: %s", err)
+	}
+
+	f, err := newJSONFile(goFile, false)
+	if err != nil {
+		t.Fatalf("newJSONFile failed: %s", err)
+	}
+	if f != nil {
+		t.Fatalf("expected no change: %#v", f)
+	}
+
+	goFile.appendDecl([]byte("func TestSub(t *testing.T) {}"))
+
+	f, err = newJSONFile(goFile, false)
+	if err != nil {
+		t.Fatalf("newJSONFile failed: %s", err)
+	}
+
+	if len(f.Edits) != 1 {
+		t.Fatalf("expected %d to eq %d", len(f.Edits), 1)
+	}
+
+	start := *f.Edits[0].Start
+	expected := jsonPos{Offset: len(src), Line: 6, Col: 1}
+	if start != expected {
+		t.Errorf("expected %v to eq %v", start, expected)
+	}
+
+	if len(f.Tests) != 1 || f.Tests[0].Name != "TestSub" || f.Tests[0].Line != 7 {
+		t.Errorf("expected TestSub at line 7: %#v", f.Tests)
+	}
+}
+
+func TestPosOf(t *testing.T) {
+	src := []byte("package p\n\nfunc Añadir() {}\n")
+
+	cases := []struct {
+		offset   int
+		expected jsonPos
+	}{
+		{0, jsonPos{0, 1, 1}},
+		{9, jsonPos{9, 1, 10}},
+		{11, jsonPos{11, 3, 1}},
+		// ñ is 2 bytes.
+		{21, jsonPos{21, 3, 11}},
+	}
+
+	for _, c := range cases {
+		if pos := *posOf(src, c.offset); pos != c.expected {
+			t.Errorf("expected %v to eq %v", pos, c.expected)
+		}
+	}
+}
+
+func TestNewJSONErrors(t *testing.T) {
+	_, err := parse("/src/p.go", strings.NewReader("package p\n\nfunc Add( {}\n"))
+	if err == nil {
+		t.Fatal("expected syntax error")
+	}
+
+	errs := newJSONErrors("/src/p.go", fmt.Errorf("failed to parse go file: %w", err))
+	if len(errs) == 0 {
+		t.Fatal("expected errors")
+	}
+
+	expected := jsonError{Path: "/src/p.go", Line: 3, Col: 11, Message: "expected ')', found '{'"}
+	if *errs[0] != expected {
+		t.Errorf("expected %v to eq %v", *errs[0], expected)
+	}
+
+	errs = newJSONErrors("/src/p.go", fmt.Errorf("no function or method at line 1"))
+	expected = jsonError{Path: "/src/p.go", Message: "no function or method at line 1"}
+	if len(errs) != 1 || *errs[0] != expected {
+		t.Errorf("expected %v to eq %v", errs, expected)
+	}
+}
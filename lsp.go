@@ -0,0 +1,461 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/textproto"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// LSP error codes (JSON-RPC).
+const (
+	lspParseError     = -32700
+	lspMethodNotFound = -32601
+	lspInternalError  = -32603
+)
+
+// lspServer is a language server which offers code actions generating
+// tests. It speaks the Language Server Protocol over stdio (`gotests lsp`).
+type lspServer struct {
+	in  *bufio.Reader
+	out io.Writer
+
+	// opts are the options of generation given to `gotests lsp`.
+	opts *generateOpts
+
+	// docs are the documents opened in the editor keyed by
+	// their absolute paths.
+	docs map[string]*lspDocument
+
+	shutdown bool
+}
+
+// lspDocument is a document opened in the editor.
+type lspDocument struct {
+	Version int
+	Text    []byte
+}
+
+// lspMessage is a JSON-RPC request, notification or response.
+type lspMessage struct {
+	JSONRPC string           `json:"jsonrpc"`
+	ID      *json.RawMessage `json:"id,omitempty"`
+	Method  string           `json:"method,omitempty"`
+	Params  json.RawMessage  `json:"params,omitempty"`
+}
+
+type lspError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+type lspPosition struct {
+	Line      int `json:"line"`
+	Character int `json:"character"`
+}
+
+type lspRange struct {
+	Start lspPosition `json:"start"`
+	End   lspPosition `json:"end"`
+}
+
+type lspTextDocument struct {
+	URI     string `json:"uri"`
+	Version *int   `json:"version,omitempty"`
+	Text    string `json:"text,omitempty"`
+}
+
+type lspTextEdit struct {
+	Range   lspRange `json:"range"`
+	NewText string   `json:"newText"`
+}
+
+// lspDocumentChange is TextDocumentEdit or CreateFile (if Kind is "create").
+type lspDocumentChange struct {
+	Kind string `json:"kind,omitempty"`
+	URI  string `json:"uri,omitempty"`
+
+	TextDocument *lspVersionedDocument `json:"textDocument,omitempty"`
+	Edits        []*lspTextEdit        `json:"edits,omitempty"`
+}
+
+type lspVersionedDocument struct {
+	URI string `json:"uri"`
+
+	// Version is null if the document is not opened.
+	Version *int `json:"version"`
+}
+
+type lspWorkspaceEdit struct {
+	DocumentChanges []*lspDocumentChange `json:"documentChanges"`
+}
+
+type lspCodeAction struct {
+	Title string            `json:"title"`
+	Kind  string            `json:"kind"`
+	Edit  *lspWorkspaceEdit `json:"edit"`
+}
+
+// lspCodeActionKind is the kind of code actions gotests offers.
+const lspCodeActionKind = "source"
+
+// newLSPServer returns lspServer reading messages from in and writing
+// messages to out.
+func newLSPServer(in io.Reader, out io.Writer, opts *generateOpts) *lspServer {
+	return &lspServer{
+		in:   bufio.NewReader(in),
+		out:  out,
+		opts: opts,
+		docs: make(map[string]*lspDocument),
+	}
+}
+
+// serve handles messages until exit notification or EOF.
+// It returns the exit code.
+func (s *lspServer) serve() int {
+	for {
+		msg, err := s.read()
+		if err == io.EOF {
+			return ExitCodeError
+		}
+		if err != nil {
+			s.respond(nil, nil, &lspError{Code: lspParseError, Message: err.Error()})
+			continue
+		}
+
+		if msg.Method == "exit" {
+			if s.shutdown {
+				return ExitCodeOK
+			}
+			return ExitCodeError
+		}
+
+		result, rerr := s.handle(msg)
+
+		// Notifications have no response.
+		if msg.ID == nil {
+			continue
+		}
+		s.respond(msg.ID, result, rerr)
+	}
+}
+
+// read reads a message with its header.
+func (s *lspServer) read() (*lspMessage, error) {
+	body, err := s.readBody()
+	if err != nil {
+		return nil, err
+	}
+
+	var msg lspMessage
+	if err := json.Unmarshal(body, &msg); err != nil {
+		return nil, err
+	}
+	return &msg, nil
+}
+
+// readBody reads the body of a message.
+func (s *lspServer) readBody() ([]byte, error) {
+	header, err := textproto.NewReader(s.in).ReadMIMEHeader()
+	if err != nil {
+		return nil, err
+	}
+
+	length, err := strconv.Atoi(header.Get("Content-Length"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid Content-Length: %s", err)
+	}
+
+	body := make([]byte, length)
+	if _, err := io.ReadFull(s.in, body); err != nil {
+		return nil, err
+	}
+	return body, nil
+}
+
+// respond writes the response of the request id.
+func (s *lspServer) respond(id *json.RawMessage, result interface{}, rerr *lspError) error {
+	res := map[string]interface{}{
+		"jsonrpc": "2.0",
+		"id":      id,
+	}
+	if rerr != nil {
+		res["error"] = rerr
+	} else {
+		res["result"] = result
+	}
+
+	body, err := json.Marshal(res)
+	if err != nil {
+		return err
+	}
+
+	_, err = fmt.Fprintf(s.out, "Content-Length: %d\r\n\r\n%s", len(body), body)
+	return err
+}
+
+// handle handles a request or a notification and returns the result.
+func (s *lspServer) handle(msg *lspMessage) (interface{}, *lspError) {
+	switch msg.Method {
+	case "initialize":
+		return map[string]interface{}{
+			"capabilities": map[string]interface{}{
+				"textDocumentSync": map[string]interface{}{
+					"openClose": true,
+					"change":    1, // Full
+				},
+				"codeActionProvider": map[string]interface{}{
+					"codeActionKinds": []string{lspCodeActionKind},
+				},
+			},
+			"serverInfo": map[string]string{
+				"name":    Name,
+				"version": Version,
+			},
+		}, nil
+
+	case "shutdown":
+		s.shutdown = true
+		return nil, nil
+
+	case "textDocument/didOpen":
+		var params struct {
+			TextDocument lspTextDocument `json:"textDocument"`
+		}
+		if err := json.Unmarshal(msg.Params, &params); err != nil {
+			return nil, &lspError{Code: lspParseError, Message: err.Error()}
+		}
+		s.setDocument(params.TextDocument.URI, params.TextDocument.Version, params.TextDocument.Text)
+		return nil, nil
+
+	case "textDocument/didChange":
+		var params struct {
+			TextDocument   lspTextDocument `json:"textDocument"`
+			ContentChanges []struct {
+				Text string `json:"text"`
+			} `json:"contentChanges"`
+		}
+		if err := json.Unmarshal(msg.Params, &params); err != nil {
+			return nil, &lspError{Code: lspParseError, Message: err.Error()}
+		}
+		if n := len(params.ContentChanges); n > 0 {
+			s.setDocument(params.TextDocument.URI, params.TextDocument.Version, params.ContentChanges[n-1].Text)
+		}
+		return nil, nil
+
+	case "textDocument/didClose":
+		var params struct {
+			TextDocument lspTextDocument `json:"textDocument"`
+		}
+		if err := json.Unmarshal(msg.Params, &params); err != nil {
+			return nil, &lspError{Code: lspParseError, Message: err.Error()}
+		}
+		delete(s.docs, uriPath(params.TextDocument.URI))
+		return nil, nil
+
+	case "textDocument/codeAction":
+		var params struct {
+			TextDocument lspTextDocument `json:"textDocument"`
+			Range        lspRange        `json:"range"`
+		}
+		if err := json.Unmarshal(msg.Params, &params); err != nil {
+			return nil, &lspError{Code: lspParseError, Message: err.Error()}
+		}
+
+		actions, err := s.codeActions(uriPath(params.TextDocument.URI), params.Range.Start)
+		if err != nil {
+			return nil, &lspError{Code: lspInternalError, Message: err.Error()}
+		}
+		return actions, nil
+	}
+
+	if msg.ID != nil {
+		return nil, &lspError{Code: lspMethodNotFound, Message: "method not found: " + msg.Method}
+	}
+	return nil, nil
+}
+
+func (s *lspServer) setDocument(uri string, version *int, text string) {
+	doc := &lspDocument{Text: []byte(text)}
+	if version != nil {
+		doc.Version = *version
+	}
+	s.docs[uriPath(uri)] = doc
+}
+
+// codeActions returns the actions generating the test of the function or
+// method at pos and the missing tests of the file. Actions which change
+// nothing are not returned.
+func (s *lspServer) codeActions(path string, pos lspPosition) ([]*lspCodeAction, error) {
+	if !strings.HasSuffix(path, ".go") || strings.HasSuffix(path, "_test.go") {
+		return []*lspCodeAction{}, nil
+	}
+
+	overlay := make(map[string][]byte)
+	for docPath, doc := range s.docs {
+		overlay[docPath] = doc.Text
+	}
+
+	goFile, err := parseFileOverlay(path, overlay)
+	if err != nil {
+		return nil, err
+	}
+
+	actions := []*lspCodeAction{}
+	if name := goFile.targetAt(offsetAt(goFile.SrcBytes, pos)); name != "" {
+		edit, err := s.workspaceEdit(path, overlay, []string{name})
+		if err != nil {
+			return nil, err
+		}
+		if edit != nil {
+			actions = append(actions, &lspCodeAction{
+				Title: "Generate test for " + name,
+				Kind:  lspCodeActionKind,
+				Edit:  edit,
+			})
+		}
+	}
+
+	edit, err := s.workspaceEdit(path, overlay, nil)
+	if err != nil {
+		return nil, err
+	}
+	if edit != nil {
+		actions = append(actions, &lspCodeAction{
+			Title: "Generate missing tests in file",
+			Kind:  lspCodeActionKind,
+			Edit:  edit,
+		})
+	}
+
+	return actions, nil
+}
+
+// workspaceEdit returns the edit generating tests of the functions and
+// methods in only (or all if it's empty) of the source file of path.
+// It returns nil if there is nothing to generate.
+func (s *lspServer) workspaceEdit(path string, overlay map[string][]byte, only []string) (*lspWorkspaceEdit, error) {
+	testPath, err := TestFilePath(path)
+	if err != nil {
+		return nil, err
+	}
+
+	opts := *s.opts
+	opts.only = only
+	opts.overlay = overlay
+
+	outFiles, err := goTestGenerate(path, testPath, &opts)
+	if err != nil {
+		return nil, err
+	}
+
+	edit := &lspWorkspaceEdit{}
+	for _, outFile := range outFiles {
+		goFile, ok := outFile.(*GoFile)
+		if !ok {
+			continue
+		}
+
+		edits, err := goFile.TextEdits()
+		if err != nil {
+			return nil, err
+		}
+		if len(edits) == 0 {
+			continue
+		}
+
+		uri := pathURI(goFile.FileName)
+		doc := &lspVersionedDocument{URI: uri}
+		if d, ok := s.docs[goFile.FileName]; ok {
+			version := d.Version
+			doc.Version = &version
+		} else if _, err := os.Stat(goFile.FileName); os.IsNotExist(err) {
+			edit.DocumentChanges = append(edit.DocumentChanges, &lspDocumentChange{
+				Kind: "create",
+				URI:  uri,
+			})
+		}
+
+		change := &lspDocumentChange{TextDocument: doc}
+		for _, e := range edits {
+			change.Edits = append(change.Edits, &lspTextEdit{
+				Range: lspRange{
+					Start: positionAt(goFile.SrcBytes, e.Offset),
+					End:   positionAt(goFile.SrcBytes, e.End),
+				},
+				NewText: string(e.Text),
+			})
+		}
+		edit.DocumentChanges = append(edit.DocumentChanges, change)
+	}
+
+	if len(edit.DocumentChanges) == 0 {
+		return nil, nil
+	}
+	return edit, nil
+}
+
+// positionAt returns the LSP position (line and UTF-16 character)
+// of offset in src.
+func positionAt(src []byte, offset int) lspPosition {
+	var pos lspPosition
+	for _, r := range string(src[:offset]) {
+		switch {
+		case r == '\n':
+			pos.Line++
+			pos.Character = 0
+		case r >= 0x10000:
+			// Surrogate pair
+			pos.Character += 2
+		default:
+			pos.Character++
+		}
+	}
+	return pos
+}
+
+// offsetAt returns the offset of LSP position in src.
+func offsetAt(src []byte, pos lspPosition) int {
+	var cur lspPosition
+	for offset, r := range string(src) {
+		if cur.Line > pos.Line || (cur.Line == pos.Line && cur.Character >= pos.Character) {
+			return offset
+		}
+
+		switch {
+		case r == '\n':
+			if cur.Line == pos.Line {
+				// Character is beyond the end of line.
+				return offset
+			}
+			cur.Line++
+			cur.Character = 0
+		case r >= 0x10000:
+			cur.Character += 2
+		default:
+			cur.Character++
+		}
+	}
+	return len(src)
+}
+
+// uriPath returns the file path of `file` URI.
+func uriPath(uri string) string {
+	u, err := url.Parse(uri)
+	if err != nil || u.Scheme != "file" {
+		return uri
+	}
+	return filepath.FromSlash(u.Path)
+}
+
+// pathURI returns `file` URI of path.
+func pathURI(path string) string {
+	u := url.URL{Scheme: "file", Path: filepath.ToSlash(path)}
+	return u.String()
+}
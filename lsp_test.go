@@ -0,0 +1,132 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestLSPServer(t *testing.T) {
+	dir, err := ioutil.TempDir("", Name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	srcPath := filepath.Join(dir, "p.go")
+	if err := ioutil.WriteFile(srcPath, []byte("package p\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	// The document is not saved yet.
+	src := "package p\n\nfunc Add(a, b int) int {\n\treturn a + b\n}\n\nfunc Sub(a, b int) int {\n\treturn a - b\n}\n"
+	uri := pathURI(srcPath)
+
+	var in bytes.Buffer
+	for i, msg := range []string{
+		`{"jsonrpc":"2.0","id":1,"method":"initialize","params":{}}`,
+		`{"jsonrpc":"2.0","method":"initialized","params":{}}`,
+		fmt.Sprintf(`{"jsonrpc":"2.0","method":"textDocument/didOpen","params":{"textDocument":{"uri":%q,"version":1,"text":%q}}}`, uri, src),
+		fmt.Sprintf(`{"jsonrpc":"2.0","id":2,"method":"textDocument/codeAction","params":{"textDocument":{"uri":%q},"range":{"start":{"line":7,"character":1},"end":{"line":7,"character":1}}}}`, uri),
+		`{"jsonrpc":"2.0","id":3,"method":"unknown"}`,
+		`{"jsonrpc":"2.0","id":4,"method":"shutdown"}`,
+		`{"jsonrpc":"2.0","method":"exit"}`,
+	} {
+		if i > 0 {
+			// Header may have Content-Type.
+			fmt.Fprint(&in, "Content-Type: application/vscode-jsonrpc; charset=utf-8\r\n")
+		}
+		fmt.Fprintf(&in, "Content-Length: %d\r\n\r\n%s", len(msg), msg)
+	}
+
+	var out bytes.Buffer
+	s := newLSPServer(&in, &out, &generateOpts{diffOpts: &diffOpts{}})
+	if code := s.serve(); code != ExitCodeOK {
+		t.Fatalf("expected %d to eq %d", code, ExitCodeOK)
+	}
+
+	var responses []map[string]json.RawMessage
+	client := newLSPServer(&out, nil, nil)
+	for {
+		body, err := client.readBody()
+		if err != nil {
+			break
+		}
+		var res map[string]json.RawMessage
+		if err := json.Unmarshal(body, &res); err != nil {
+			t.Fatal(err)
+		}
+		responses = append(responses, res)
+	}
+
+	if len(responses) != 4 {
+		t.Fatalf("expected %d to eq %d", len(responses), 4)
+	}
+
+	var actions []*lspCodeAction
+	if err := json.Unmarshal(responses[1]["result"], &actions); err != nil {
+		t.Fatal(err)
+	}
+
+	var titles []string
+	for _, action := range actions {
+		titles = append(titles, action.Title)
+	}
+	expected := "Generate test for Sub, Generate missing tests in file"
+	if got := strings.Join(titles, ", "); got != expected {
+		t.Fatalf("expected %q to eq %q", got, expected)
+	}
+
+	// The test file does not exist and is created.
+	changes := actions[0].Edit.DocumentChanges
+	if len(changes) != 2 || changes[0].Kind != "create" {
+		t.Fatalf("expected create and edit: %#v", changes)
+	}
+	if changes[1].TextDocument.URI != pathURI(filepath.Join(dir, "p_test.go")) {
+		t.Errorf("expected %q to eq p_test.go", changes[1].TextDocument.URI)
+	}
+
+	text := changes[1].Edits[0].NewText
+	if !strings.Contains(text, "func TestSub(t *testing.T)") || strings.Contains(text, "TestAdd") {
+		t.Errorf("expected only TestSub: %s", text)
+	}
+
+	if e := responses[2]["error"]; !bytes.Contains(e, []byte("-32601")) {
+		t.Errorf("expected method not found error: %s", e)
+	}
+}
+
+func TestPositionAt(t *testing.T) {
+	src := []byte("package p\n\n// 𝔸dd adds.\nfunc Add() {}\n")
+
+	cases := []struct {
+		offset int
+		pos    lspPosition
+	}{
+		{0, lspPosition{0, 0}},
+		{10, lspPosition{1, 0}},
+		{11, lspPosition{2, 0}},
+		// 𝔸 is 4 bytes in UTF-8 and 2 units in UTF-16.
+		{18, lspPosition{2, 5}},
+		{len(src), lspPosition{4, 0}},
+	}
+
+	for _, c := range cases {
+		if pos := positionAt(src, c.offset); pos != c.pos {
+			t.Errorf("expected %v to eq %v", pos, c.pos)
+		}
+		if offset := offsetAt(src, c.pos); offset != c.offset {
+			t.Errorf("expected %d to eq %d", offset, c.offset)
+		}
+	}
+
+	// Character beyond the end of line is the end of line.
+	if offset := offsetAt(src, lspPosition{0, 100}); offset != 9 {
+		t.Errorf("expected %d to eq %d", offset, 9)
+	}
+}
@@ -0,0 +1,333 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"go/ast"
+	"go/format"
+	"path/filepath"
+	"strings"
+	"text/template"
+)
+
+// Interface is an interface type declared in package.
+type Interface struct {
+	Name string
+
+	// FileName is the file which declares the interface.
+	FileName string
+
+	Type *ast.InterfaceType
+}
+
+// InterfaceMethod is a method of interface.
+type InterfaceMethod struct {
+	Name      string
+	Signature *Signature
+}
+
+// packageInterfaces returns interfaces declared in goFiles keyed by name.
+// Generic interfaces and constraints (which have type elements) are
+// not included since fakes can't be generated for them.
+func packageInterfaces(goFiles []*GoFile) map[string]*Interface {
+	ifaces := make(map[string]*Interface)
+	for _, goFile := range goFiles {
+		for _, typ := range goFile.Types {
+			ifaceType, ok := typ.Spec.Type.(*ast.InterfaceType)
+			if !ok || typ.Spec.TypeParams != nil || hasTypeElems(ifaceType) {
+				continue
+			}
+
+			ifaces[typ.Name] = &Interface{
+				Name:     typ.Name,
+				FileName: goFile.FileName,
+				Type:     ifaceType,
+			}
+		}
+	}
+	return ifaces
+}
+
+// hasTypeElems returns true if interface has type elements
+// (e.g., `~int | string`), i.e., it's a constraint.
+func hasTypeElems(ifaceType *ast.InterfaceType) bool {
+	for _, field := range ifaceType.Methods.List {
+		if len(field.Names) > 0 {
+			continue
+		}
+
+		switch field.Type.(type) {
+		case *ast.Ident, *ast.SelectorExpr:
+		default:
+			return true
+		}
+	}
+	return false
+}
+
+// Methods returns methods of the interface including the ones of
+// embedded interfaces declared in package (ifaces). Methods of the
+// other embedded interfaces (e.g., io.Reader) are not included:
+// fake embeds the interface itself so that it still satisfies it.
+func (iface *Interface) Methods(ifaces map[string]*Interface) []*InterfaceMethod {
+	return iface.methods(ifaces, make(map[string]bool))
+}
+
+func (iface *Interface) methods(ifaces map[string]*Interface, visited map[string]bool) []*InterfaceMethod {
+	visited[iface.Name] = true
+
+	var methods []*InterfaceMethod
+	for _, field := range iface.Type.Methods.List {
+		if funcType, ok := field.Type.(*ast.FuncType); ok {
+			for _, name := range field.Names {
+				methods = append(methods, &InterfaceMethod{
+					Name:      name.Name,
+					Signature: NewSignature(funcType, nil),
+				})
+			}
+			continue
+		}
+
+		embedded, ok := ifaces[typeName(field.Type)]
+		if !ok || visited[embedded.Name] {
+			continue
+		}
+		methods = append(methods, embedded.methods(ifaces, visited)...)
+	}
+
+	return methods
+}
+
+// fakeTypeName returns the name of fake type of interface.
+func fakeTypeName(ifaceName string) string {
+	return "fake" + strings.Title(ifaceName)
+}
+
+// mockFilePath returns the path of the file for fakes of
+// interfaces declared in srcPath.
+func mockFilePath(srcPath string) string {
+	dir, file := filepath.Split(srcPath)
+	return filepath.Join(dir, "mock_"+strings.TrimSuffix(file, ".go")+"_test.go")
+}
+
+// addFakes adds fakes of interfaces which are declared in goFile or used
+// as params of its functions/methods. Fakes are added to the mock file
+// of the file declaring the interface unless the package test files
+// already have them. It returns the mock files and fake type names
+// keyed by interface names. In external test package, interface names
+// are qualified (e.g., `p.Store`) and fakes refer to them in the same way.
+func addFakes(goFile *GoFile, testPkgName string) ([]*GoFile, map[string]string, error) {
+	dir := filepath.Dir(goFile.FileName)
+
+	pkgFiles, err := parsePackageFiles(dir)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to parse package files: %w", err)
+	}
+	ifaces := packageInterfaces(pkgFiles)
+
+	var names map[string]ast.Expr
+	importPath := ""
+	if isExternalTest(testPkgName) {
+		names = qualifiedNames(goFile.PackageName, pkgFiles)
+		importPath = packageImportPath(goFile.FileName)
+	}
+
+	testFiles, err := parseTestFiles(dir)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to parse test files: %w", err)
+	}
+
+	existing := make(map[string]bool)
+	for _, testFile := range testFiles {
+		for _, typ := range testFile.Types {
+			existing[typ.Name] = true
+		}
+	}
+
+	var mockFiles []*GoFile
+	fakes := make(map[string]string)
+	for _, name := range referredInterfaces(goFile, ifaces) {
+		iface := ifaces[name]
+		fakeName := fakeTypeName(name)
+		fakes[substituteString(name, names)] = fakeName
+
+		if existing[fakeName] {
+			continue
+		}
+		existing[fakeName] = true
+
+		mockPath := mockFilePath(iface.FileName)
+		var mockFile *GoFile
+		for _, f := range mockFiles {
+			if f.FileName == mockPath {
+				mockFile = f
+			}
+		}
+
+		if mockFile == nil {
+			mockFile, err = openTestFile(mockPath, testPkgName, nil)
+			if err != nil {
+				return nil, nil, err
+			}
+			mockFiles = append(mockFiles, mockFile)
+			if importPath != "" {
+				mockFile.addImport("", importPath)
+			}
+		}
+
+		text, err := buildFake(fakeName, iface, iface.Methods(ifaces), names)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to build fake of %s: %s", name, err)
+		}
+		mockFile.appendDecl(text)
+	}
+
+	return mockFiles, fakes, nil
+}
+
+// referredInterfaces returns names of interfaces (in ifaces) declared
+// in goFile or used as params of its functions and methods. ifaces has only
+// the interfaces declared in the package, so ones of other packages are not
+// faked (and embedding them leaves their methods unimplemented).
+func referredInterfaces(goFile *GoFile, ifaces map[string]*Interface) []string {
+	var names []string
+	add := func(name string) {
+		if _, ok := ifaces[name]; ok && !contains(names, name) {
+			names = append(names, name)
+		}
+	}
+
+	for _, typ := range goFile.Types {
+		add(typ.Name)
+	}
+
+	for _, fun := range goFile.Funcs {
+		for _, param := range fun.Signature.Params {
+			add(typeName(param.Type))
+		}
+	}
+
+	for _, method := range goFile.Methods {
+		for _, param := range method.Signature.Params {
+			add(typeName(param.Type))
+		}
+	}
+
+	return names
+}
+
+// fakeTmpl is the template of fake type and its methods.
+var fakeTmpl = template.Must(template.New("fake").Parse(`
+// {{ .Name }} is a fake of {{ .Interface }} for testing.
+// Set XxxFunc field to change the behavior of Xxx method.
+type {{ .Name }} struct {
+	{{ .Interface }}
+{{ range .Methods }}
+	{{ .Name }}Func func({{ .Params }}) {{ .ResultTypes }}
+{{- end }}
+}
+{{ range .Methods }}
+func (fake *{{ $.Name }}) {{ .Name }}({{ .Params }}) {{ .NamedResults }} {
+	if fake.{{ .Name }}Func != nil {
+		{{ if .NamedResults }}return {{ end }}fake.{{ .Name }}Func({{ .Args }})
+	}
+	{{- if .NamedResults }}
+	return
+	{{- end }}
+}
+{{ end }}
+`))
+
+// fakeMethod is the data of fakeTmpl for each method.
+type fakeMethod struct {
+	Name string
+
+	// Params is params with names (e.g., `key string, opts ...Option`).
+	Params string
+
+	// Args is args to call the function field (e.g., `key, opts...`).
+	Args string
+
+	// ResultTypes is result types for the function field.
+	ResultTypes string
+
+	// NamedResults is results with names for the method. Named
+	// results allow returning zero values when the field is nil.
+	NamedResults string
+}
+
+// buildFake builds the source of fake type (and its methods) of iface.
+// Identifiers of the package are qualified with names (see qualifiedNames)
+// in external test package. names is nil otherwise.
+func buildFake(fakeName string, iface *Interface, methods []*InterfaceMethod, names map[string]ast.Expr) ([]byte, error) {
+	data := struct {
+		Name      string
+		Interface string
+		Methods   []*fakeMethod
+	}{
+		Name:      fakeName,
+		Interface: substituteString(iface.Name, names),
+	}
+
+	for _, method := range methods {
+		sig := method.Signature
+		sig.qualify(names)
+		names := sig.ParamNames()
+
+		var params, args []string
+		for i, param := range sig.Params {
+			// "fake" is the receiver name.
+			if names[i] == "fake" {
+				names[i] = fmt.Sprintf("arg%d", i)
+			}
+
+			params = append(params, names[i]+" "+param.TypeString())
+			if _, ok := param.Type.(*ast.Ellipsis); ok {
+				args = append(args, names[i]+"...")
+			} else {
+				args = append(args, names[i])
+			}
+		}
+
+		var types, named []string
+		for i, result := range sig.Results {
+			types = append(types, result.TypeString())
+
+			name := result.Name
+			if name == "" || name == "_" {
+				name = fmt.Sprintf("r%d", i)
+			}
+			named = append(named, name+" "+result.TypeString())
+		}
+
+		fm := &fakeMethod{
+			Name:   method.Name,
+			Params: joinArgs(params),
+			Args:   joinArgs(args),
+		}
+
+		switch len(types) {
+		case 0:
+		case 1:
+			fm.ResultTypes = types[0]
+			fm.NamedResults = "(" + named[0] + ")"
+		default:
+			fm.ResultTypes = "(" + joinArgs(types) + ")"
+			fm.NamedResults = "(" + joinArgs(named) + ")"
+		}
+
+		data.Methods = append(data.Methods, fm)
+	}
+
+	var buf bytes.Buffer
+	if err := fakeTmpl.Execute(&buf, data); err != nil {
+		return nil, err
+	}
+
+	src, err := format.Source(buf.Bytes())
+	if err != nil {
+		return nil, err
+	}
+
+	return bytes.TrimSpace(src), nil
+}
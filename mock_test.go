@@ -0,0 +1,112 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestBuildFake(t *testing.T) {
+	src := `package p
+
+type Store interface {
+	Getter
+	io.Closer
+	Put(key string, values ...string) error
+}
+
+type Getter interface {
+	Get(string) (string, bool)
+}
+
+type Number interface {
+	~int | ~float64
+}
+`
+	goFile, err := parse("p.go", strings.NewReader(src))
+	if err != nil {
+		t.Fatalf("parse failed: %s", err)
+	}
+
+	ifaces := packageInterfaces([]*GoFile{goFile})
+	if _, ok := ifaces["Number"]; ok {
+		t.Fatalf("expected constraint not to be included")
+	}
+
+	store := ifaces["Store"]
+	res, err := buildFake("fakeStore", store, store.Methods(ifaces), nil)
+	if err != nil {
+		t.Fatalf("buildFake failed: %s", err)
+	}
+
+	expected := `// fakeStore is a fake of Store for testing.
+// Set XxxFunc field to change the behavior of Xxx method.
+type fakeStore struct {
+	Store
+
+	GetFunc func(arg0 string) (string, bool)
+	PutFunc func(key string, values ...string) error
+}
+
+func (fake *fakeStore) Get(arg0 string) (r0 string, r1 bool) {
+	if fake.GetFunc != nil {
+		return fake.GetFunc(arg0)
+	}
+	return
+}
+
+func (fake *fakeStore) Put(key string, values ...string) (r0 error) {
+	if fake.PutFunc != nil {
+		return fake.PutFunc(key, values...)
+	}
+	return
+}`
+	if string(res) != expected {
+		t.Errorf("expected %q to eq %q", res, expected)
+	}
+}
+
+func TestMockFilePath(t *testing.T) {
+	if res := mockFilePath("/src/store.go"); res != "/src/mock_store_test.go" {
+		t.Errorf("expected %q to eq %q", res, "/src/mock_store_test.go")
+	}
+}
+
+func TestBuildFake_external(t *testing.T) {
+	src := `package p
+
+type Item struct{}
+
+type Store interface {
+	Get(id int) (*Item, error)
+}
+`
+	goFile, err := parse("p.go", strings.NewReader(src))
+	if err != nil {
+		t.Fatalf("parse failed: %s", err)
+	}
+
+	ifaces := packageInterfaces([]*GoFile{goFile})
+	store := ifaces["Store"]
+	res, err := buildFake("fakeStore", store, store.Methods(ifaces), qualifiedNames("p", []*GoFile{goFile}))
+	if err != nil {
+		t.Fatalf("buildFake failed: %s", err)
+	}
+
+	expected := `// fakeStore is a fake of p.Store for testing.
+// Set XxxFunc field to change the behavior of Xxx method.
+type fakeStore struct {
+	p.Store
+
+	GetFunc func(id int) (*p.Item, error)
+}
+
+func (fake *fakeStore) Get(id int) (r0 *p.Item, r1 error) {
+	if fake.GetFunc != nil {
+		return fake.GetFunc(id)
+	}
+	return
+}`
+	if string(res) != expected {
+		t.Errorf("expected %q to eq %q", res, expected)
+	}
+}
@@ -0,0 +1,212 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"go/ast"
+	"go/token"
+	"sort"
+	"strings"
+)
+
+// Order is the order in which generated test functions are inserted
+// into test file.
+type Order int
+
+const (
+	// Append adds generated test functions to the end of test file.
+	Append Order = iota
+
+	// SourceOrder inserts generated test functions next to the tests of
+	// neighboring source declarations so that test file mirrors source order.
+	SourceOrder
+
+	// Alphabetical inserts generated test functions so that test
+	// functions are sorted by name.
+	Alphabetical
+)
+
+// ParseOrder returns Order from its flag value.
+func ParseOrder(s string) (Order, error) {
+	switch s {
+	case "", "append":
+		return Append, nil
+	case "source":
+		return SourceOrder, nil
+	case "alpha":
+		return Alphabetical, nil
+	default:
+		return Append, fmt.Errorf("unknown order %q (must be append, source or alpha)", s)
+	}
+}
+
+// placedDecl is a generated declaration waiting to be placed.
+type placedDecl struct {
+	Name string
+	Pos  token.Pos
+	Text []byte
+}
+
+// placer decides where generated declarations are inserted into
+// test file and adds them as edits of the file.
+type placer struct {
+	order    Order
+	testFile *GoFile
+
+	// srcTests are expected test function names of source
+	// declarations in source order. It's used only for SourceOrder.
+	srcTests []string
+
+	decls []*placedDecl
+}
+
+func newPlacer(order Order, testFile *GoFile, srcTests []string) *placer {
+	return &placer{
+		order:    order,
+		testFile: testFile,
+		srcTests: srcTests,
+	}
+}
+
+// add registers the declaration source with the given name. pos is
+// the position of the target declaration in source file.
+func (p *placer) add(name string, pos token.Pos, text []byte) {
+	p.decls = append(p.decls, &placedDecl{
+		Name: name,
+		Pos:  pos,
+		Text: text,
+	})
+}
+
+// place adds all registered declarations to test file.
+func (p *placer) place() {
+	switch p.order {
+	case SourceOrder:
+		sort.SliceStable(p.decls, func(i, j int) bool {
+			return p.decls[i].Pos < p.decls[j].Pos
+		})
+	case Alphabetical:
+		sort.SliceStable(p.decls, func(i, j int) bool {
+			return p.decls[i].Name < p.decls[j].Name
+		})
+	}
+
+	for _, decl := range p.decls {
+		switch p.order {
+		case SourceOrder:
+			p.placeSourceOrder(decl)
+		case Alphabetical:
+			p.placeAlphabetical(decl)
+		default:
+			p.testFile.appendDecl(decl.Text)
+		}
+	}
+
+	p.decls = nil
+}
+
+// placeSourceOrder inserts decl after the existing test of the nearest
+// preceding source declaration. If there is no such test, it inserts
+// decl before the existing test of the nearest following one.
+func (p *placer) placeSourceOrder(decl *placedDecl) {
+	idx := -1
+	for i, name := range p.srcTests {
+		if name == decl.Name {
+			idx = i
+			break
+		}
+	}
+
+	if idx < 0 {
+		p.testFile.appendDecl(decl.Text)
+		return
+	}
+
+	for i := idx - 1; i >= 0; i-- {
+		if anchor := p.testFile.funcDecl(p.srcTests[i]); anchor != nil {
+			p.testFile.insertDeclAfter(anchor, decl.Text)
+			return
+		}
+	}
+
+	for i := idx + 1; i < len(p.srcTests); i++ {
+		if anchor := p.testFile.funcDecl(p.srcTests[i]); anchor != nil {
+			p.testFile.insertDeclBefore(anchor, decl.Text)
+			return
+		}
+	}
+
+	p.testFile.appendDecl(decl.Text)
+}
+
+// placeAlphabetical inserts decl before the first existing test function
+// whose name is greater than decl's.
+func (p *placer) placeAlphabetical(decl *placedDecl) {
+	for _, d := range p.testFile.AstFile.Decls {
+		funcDecl, ok := d.(*ast.FuncDecl)
+		if !ok || funcDecl.Recv != nil || !isTestingFunc(funcDecl.Name.Name) || p.testFile.removed[funcDecl] {
+			continue
+		}
+
+		if decl.Name < funcDecl.Name.Name {
+			p.testFile.insertDeclBefore(funcDecl, decl.Text)
+			return
+		}
+	}
+
+	p.testFile.appendDecl(decl.Text)
+}
+
+// testingFuncPrefixes are name prefixes of functions which
+// go test handles specially.
+var testingFuncPrefixes = []string{"Test", "Benchmark", "Example", "Fuzz"}
+
+// isTestingFunc returns true if name is a name of function
+// which go test handles specially.
+func isTestingFunc(name string) bool {
+	for _, prefix := range testingFuncPrefixes {
+		if strings.HasPrefix(name, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// funcDecl returns the top-level function declaration with
+// the given name. It returns nil if there is no such function.
+func (gf *GoFile) funcDecl(name string) *ast.FuncDecl {
+	for _, decl := range gf.AstFile.Decls {
+		funcDecl, ok := decl.(*ast.FuncDecl)
+		if ok && funcDecl.Recv == nil && funcDecl.Name.Name == name {
+			return funcDecl
+		}
+	}
+	return nil
+}
+
+// insertDeclAfter inserts declaration source right after
+// the anchor declaration.
+func (gf *GoFile) insertDeclAfter(anchor ast.Decl, text []byte) {
+	gf.edits = append(gf.edits, &edit{
+		Offset: gf.FSet.Position(anchor.End()).Offset,
+		Text:   append([]byte("\n\n"), text...),
+	})
+}
+
+// insertDeclBefore inserts declaration source right before
+// the anchor declaration (and its doc comment).
+func (gf *GoFile) insertDeclBefore(anchor *ast.FuncDecl, text []byte) {
+	pos := anchor.Pos()
+	if anchor.Doc != nil {
+		pos = anchor.Doc.Pos()
+	}
+
+	var buf bytes.Buffer
+	buf.Write(text)
+	buf.WriteString("\n\n")
+
+	gf.edits = append(gf.edits, &edit{
+		Offset: gf.FSet.Position(pos).Offset,
+		Text:   buf.Bytes(),
+	})
+}
@@ -0,0 +1,155 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParseOrder(t *testing.T) {
+	cases := []struct {
+		in      string
+		success bool
+		order   Order
+	}{
+		{"", true, Append},
+		{"append", true, Append},
+		{"source", true, SourceOrder},
+		{"alpha", true, Alphabetical},
+		{"random", false, Append},
+	}
+
+	for _, tc := range cases {
+		order, err := ParseOrder(tc.in)
+		if tc.success != (err == nil) {
+			t.Fatalf("%q: expected success to be %t, err: %v", tc.in, tc.success, err)
+		}
+
+		if order != tc.order {
+			t.Errorf("%q: expected %d to eq %d", tc.in, order, tc.order)
+		}
+	}
+}
+
+func TestPlacer_place(t *testing.T) {
+	src := `package basic
+
+func A() {}
+
+func B() {}
+
+func C() {}
+
+func D() {}
+`
+
+	testSrc := `package basic
+
+import "testing"
+
+// TestB tests B.
+func TestB(t *testing.T) {}
+
+func TestD(t *testing.T) {}
+`
+
+	cases := []struct {
+		order    Order
+		expected string
+	}{
+		{
+			order: SourceOrder,
+			expected: `package basic
+
+import "testing"
+
+func TestA(t *testing.T) {
+}
+
+// TestB tests B.
+func TestB(t *testing.T) {}
+
+func TestC(t *testing.T) {
+}
+
+func TestD(t *testing.T) {}
+`,
+		},
+
+		{
+			order: Alphabetical,
+			expected: `package basic
+
+import "testing"
+
+func TestA(t *testing.T) {
+}
+
+// TestB tests B.
+func TestB(t *testing.T) {}
+
+func TestC(t *testing.T) {
+}
+
+func TestD(t *testing.T) {}
+`,
+		},
+
+		{
+			order: Append,
+			expected: `package basic
+
+import "testing"
+
+// TestB tests B.
+func TestB(t *testing.T) {}
+
+func TestD(t *testing.T) {}
+
+func TestA(t *testing.T) {
+}
+
+func TestC(t *testing.T) {
+}
+`,
+		},
+	}
+
+	for _, tc := range cases {
+		goFile, err := parse("basic.go", strings.NewReader(src))
+		if err != nil {
+			t.Fatalf("parse failed: %s", err)
+		}
+
+		goTestFile, err := parse("basic_test.go", strings.NewReader(testSrc))
+		if err != nil {
+			t.Fatalf("parse failed: %s", err)
+		}
+
+		opts := &diffOpts{}
+		srcTests, err := goFile.expectTestFuncs(opts)
+		if err != nil {
+			t.Fatalf("expectTestFuncs failed: %s", err)
+		}
+
+		diffFuncs, err := goFile.diffFuncs(goTestFile, opts)
+		if err != nil {
+			t.Fatalf("diffFuncs failed: %s", err)
+		}
+
+		p := newPlacer(tc.order, goTestFile, srcTests)
+		if err := goTestFile.addFuncTestFuncs(diffFuncs, defaultExpectTestFuncTmpl, (&testBuilder{}).build, p); err != nil {
+			t.Fatalf("addFuncTestFuncs failed: %s", err)
+		}
+
+		p.place()
+
+		res, err := goTestFile.Generate()
+		if err != nil {
+			t.Fatalf("Generate failed: %s", err)
+		}
+
+		if string(res) != tc.expected {
+			t.Errorf("order %d: expected %q to eq %q", tc.order, res, tc.expected)
+		}
+	}
+}
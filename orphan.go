@@ -0,0 +1,318 @@
+package main
+
+import (
+	"go/ast"
+	"go/types"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Orphan is a test function whose target function or method
+// does not exist in package.
+type Orphan struct {
+	Decl *ast.FuncDecl
+
+	// Target is the name of the target mapped back from the test name
+	// (e.g., `User.Add` for `TestUser_Add`).
+	Target string
+}
+
+// Name returns the name of the test function.
+func (o *Orphan) Name() string {
+	return o.Decl.Name.Name
+}
+
+// findOrphans returns tests in goTestFile whose targets are not declared
+// in pkgFiles. Test names are mapped back to targets by the naming
+// templates of opts (see orphanTarget). Tests which are not mapped back
+// to a target clearly, and TestMain, are not orphans.
+func findOrphans(goTestFile *GoFile, pkgFiles []*GoFile, opts *diffOpts) ([]*Orphan, error) {
+	opts.init()
+
+	funcPat, err := reverseFuncTmpl(opts.ExpectTestFuncTmpl, &Func{Name: nameMark})
+	if err != nil {
+		return nil, err
+	}
+
+	methodPat, err := reverseFuncTmpl(opts.ExpectTestFuncMethodTmpl, &Method{RecvName: recvNameMark, Name: nameMark})
+	if err != nil {
+		return nil, err
+	}
+
+	decls := newPackageDecls(pkgFiles)
+
+	var orphans []*Orphan
+	for _, decl := range goTestFile.AstFile.Decls {
+		funcDecl, ok := decl.(*ast.FuncDecl)
+		if !ok || !isTestFuncDecl(funcDecl) {
+			continue
+		}
+
+		target, err := decls.orphanTarget(funcDecl.Name.Name, funcPat, methodPat, opts)
+		if err != nil {
+			return nil, err
+		}
+		if target != "" {
+			orphans = append(orphans, &Orphan{Decl: funcDecl, Target: target})
+		}
+	}
+
+	return orphans, nil
+}
+
+// packageDecls are the names of the functions, methods and types declared
+// in package keyed by targetKey.
+type packageDecls struct {
+	funcs   map[string]bool
+	methods map[string]bool
+	types   map[string]bool
+}
+
+func newPackageDecls(pkgFiles []*GoFile) *packageDecls {
+	decls := &packageDecls{
+		funcs:   make(map[string]bool),
+		methods: make(map[string]bool),
+		types:   make(map[string]bool),
+	}
+
+	for _, goFile := range pkgFiles {
+		for _, fun := range goFile.Funcs {
+			decls.funcs[targetKey(fun.Name)] = true
+		}
+
+		for _, method := range goFile.Methods {
+			decls.methods[targetKey(method.RecvName)+"."+targetKey(method.Name)] = true
+			decls.types[targetKey(method.RecvName)] = true
+		}
+
+		for _, typ := range goFile.Types {
+			decls.types[targetKey(typ.Name)] = true
+		}
+	}
+
+	return decls
+}
+
+// targetKey returns name in lower case since test names have the names
+// of targets in other cases (e.g., `TestUser_Add` for `user.add` or
+// `TestLSPServer` for `lspServer`).
+func targetKey(name string) string {
+	return strings.ToLower(name)
+}
+
+// orphanTarget returns the target (e.g., `User.Add`) of the test of name
+// which is not declared, or empty string if the test is not an orphan.
+//
+// The test is not an orphan if any way to map its name back (or its name
+// cut at `_`, e.g., `TestUser_Add_error` for variations) refers to a
+// declared function or method, or its name refers to a declared type
+// (e.g., `TestUser`). Otherwise, it's an orphan only if it's mapped back
+// to exactly one target whose test name is generated as it is by the
+// templates: a function or a method of a declared type without `_`.
+// So hand-written names (e.g., `TestUser_validation`) are not orphans.
+func (d *packageDecls) orphanTarget(name string, funcPat, methodPat *funcTmplPattern, opts *diffOpts) (string, error) {
+	for n := len(name); n > 0; n = strings.LastIndex(name[:n], "_") {
+		for _, m := range funcPat.matches(name[:n]) {
+			if d.funcs[targetKey(m[nameMark])] {
+				return "", nil
+			}
+		}
+
+		for _, m := range methodPat.matches(name[:n]) {
+			if d.methods[targetKey(m[recvNameMark])+"."+targetKey(m[nameMark])] {
+				return "", nil
+			}
+		}
+	}
+
+	var targets []string
+	for _, m := range funcPat.matches(name) {
+		if d.types[targetKey(m[nameMark])] {
+			return "", nil
+		}
+		if strings.Contains(m[nameMark], "_") {
+			continue
+		}
+
+		expected, err := execFuncTmpl(opts.ExpectTestFuncTmpl, &Func{Name: m[nameMark]})
+		if err != nil {
+			return "", err
+		}
+		if expected == name {
+			targets = append(targets, m[nameMark])
+		}
+	}
+
+	for _, m := range methodPat.matches(name) {
+		if !d.types[targetKey(m[recvNameMark])] || strings.Contains(m[nameMark], "_") {
+			continue
+		}
+
+		expected, err := execFuncTmpl(opts.ExpectTestFuncMethodTmpl, &Method{RecvName: m[recvNameMark], Name: m[nameMark]})
+		if err != nil {
+			return "", err
+		}
+		if expected == name {
+			targets = append(targets, m[recvNameMark]+"."+m[nameMark])
+		}
+	}
+
+	if len(targets) != 1 {
+		return "", nil
+	}
+	return targets[0], nil
+}
+
+// isTestFuncDecl returns true if decl is a test function
+// (`func TestXxx(t *testing.T)`) other than TestMain.
+func isTestFuncDecl(decl *ast.FuncDecl) bool {
+	if decl.Recv != nil || !strings.HasPrefix(decl.Name.Name, "Test") || decl.Name.Name == "TestMain" {
+		return false
+	}
+
+	params := decl.Type.Params.List
+	return len(params) == 1 && len(params[0].Names) <= 1 &&
+		types.ExprString(params[0].Type) == "*testing.T"
+}
+
+// Marks are placeholders of names in test name template. They are not
+// letters so that `title` does not change them.
+const (
+	nameMark     = "\x01"
+	recvNameMark = "\x02"
+)
+
+// funcTmplPattern matches test names generated by test name template.
+type funcTmplPattern struct {
+	// parts are the literal parts and the marks of the names in order.
+	parts []string
+}
+
+// reverseFuncTmpl returns the pattern which matches test names generated
+// by funcTmpl. data has marks instead of names.
+func reverseFuncTmpl(funcTmpl string, data interface{}) (*funcTmplPattern, error) {
+	name, err := execFuncTmpl(funcTmpl, data)
+	if err != nil {
+		return nil, err
+	}
+
+	p := &funcTmplPattern{}
+	var literal strings.Builder
+	for _, r := range name {
+		switch s := string(r); s {
+		case nameMark, recvNameMark:
+			if literal.Len() > 0 {
+				p.parts = append(p.parts, literal.String())
+				literal.Reset()
+			}
+			p.parts = append(p.parts, s)
+		default:
+			literal.WriteRune(r)
+		}
+	}
+	if literal.Len() > 0 {
+		p.parts = append(p.parts, literal.String())
+	}
+
+	return p, nil
+}
+
+// matches returns every way to split test name into the names (keyed by
+// their marks) which generate it. Names are non-empty words. For example,
+// `TestA_B_C` is `A` and `B_C`, or `A_B` and `C` with the method template.
+func (p *funcTmplPattern) matches(name string) []map[string]string {
+	var res []map[string]string
+	names := make(map[string]string)
+
+	var match func(i int, rest string)
+	match = func(i int, rest string) {
+		if i == len(p.parts) {
+			if rest == "" {
+				m := make(map[string]string, len(names))
+				for mark, name := range names {
+					m[mark] = name
+				}
+				res = append(res, m)
+			}
+			return
+		}
+
+		part := p.parts[i]
+		if part != nameMark && part != recvNameMark {
+			if strings.HasPrefix(rest, part) {
+				match(i+1, rest[len(part):])
+			}
+			return
+		}
+
+		for n, r := range rest {
+			if !(r == '_' || unicode.IsLetter(r) || unicode.IsDigit(r)) {
+				break
+			}
+			n += utf8.RuneLen(r)
+			names[part] = rest[:n]
+			match(i+1, rest[n:])
+		}
+		delete(names, part)
+	}
+
+	match(0, name)
+	return res
+}
+
+// isEmptyTest returns true if test function does nothing
+// (except calling t.Parallel).
+func isEmptyTest(decl *ast.FuncDecl) bool {
+	for _, stmt := range decl.Body.List {
+		expr, ok := stmt.(*ast.ExprStmt)
+		if !ok || !strings.HasSuffix(types.ExprString(expr.X), ".Parallel()") {
+			return false
+		}
+	}
+	return true
+}
+
+// pruneOrphans deletes empty orphan tests. The others are kept as they
+// are since their test names may refer to targets in ways the naming
+// templates don't know.
+func (gf *GoFile) pruneOrphans(orphans []*Orphan) {
+	for _, orphan := range orphans {
+		if isEmptyTest(orphan.Decl) {
+			gf.removeDecl(orphan.Decl, nil)
+		}
+	}
+}
+
+// declRange returns the offsets of decl (including its doc comment)
+// in source.
+func (gf *GoFile) declRange(decl *ast.FuncDecl) (int, int) {
+	pos := decl.Pos()
+	if decl.Doc != nil {
+		pos = decl.Doc.Pos()
+	}
+	return gf.FSet.Position(pos).Offset, gf.FSet.Position(decl.End()).Offset
+}
+
+// removeDecl replaces decl (and its doc comment) with text. If text is
+// nil, decl is deleted with the blank lines before it. Removed decl is
+// not used as an anchor of inserted declarations (see placer).
+func (gf *GoFile) removeDecl(decl *ast.FuncDecl, text []byte) {
+	start, end := gf.declRange(decl)
+	if text == nil {
+		for start > 0 && unicode.IsSpace(rune(gf.SrcBytes[start-1])) {
+			start--
+		}
+	}
+
+	gf.edits = append(gf.edits, &edit{
+		Offset: start,
+		End:    end,
+		Text:   text,
+	})
+
+	if gf.removed == nil {
+		gf.removed = make(map[*ast.FuncDecl]bool)
+	}
+	gf.removed[decl] = true
+}
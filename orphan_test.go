@@ -0,0 +1,191 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestFindOrphans(t *testing.T) {
+	src := `package p
+
+type User struct{}
+
+func (u *User) Insert(name string) error { return nil }
+
+func (u *User) validateAt(line int) error { return nil }
+
+type placer struct{}
+
+func (p *placer) place() {}
+
+type Item struct{}
+
+func Helper() {}
+`
+	testSrc := `package p
+
+import "testing"
+
+func TestMain(m *testing.M) {}
+
+func TestUser_Add(t *testing.T) {}
+
+func TestUser_Insert(t *testing.T) {}
+
+func TestUser_Insert_noName(t *testing.T) {}
+
+func TestUser_validateAt(t *testing.T) {}
+
+func TestUser_ValidateAt(t *testing.T) {}
+
+func TestPlacer_place(t *testing.T) {}
+
+func TestHelper(t *testing.T) {}
+
+func TestHelper_error(t *testing.T) {}
+
+func TestRemoved(t *testing.T) {}
+
+func TestRemoved_error(t *testing.T) {}
+
+func TestOld_Add(t *testing.T) {}
+
+func TestItem_Add_Remove(t *testing.T) {}
+
+func TestItem(t *testing.T) {}
+
+func TestItem_validation(t *testing.T) {}
+
+func TestSetup() {}
+`
+	goFile, err := parse("p.go", strings.NewReader(src))
+	if err != nil {
+		t.Fatalf("parse failed: %s", err)
+	}
+
+	goTestFile, err := parse("p_test.go", strings.NewReader(testSrc))
+	if err != nil {
+		t.Fatalf("parse failed: %s", err)
+	}
+
+	orphans, err := findOrphans(goTestFile, []*GoFile{goFile}, &diffOpts{})
+	if err != nil {
+		t.Fatalf("findOrphans failed: %s", err)
+	}
+
+	var res []string
+	for _, orphan := range orphans {
+		res = append(res, orphan.Name()+": "+orphan.Target)
+	}
+
+	expected := "TestUser_Add: User.Add, TestRemoved: Removed"
+	if got := strings.Join(res, ", "); got != expected {
+		t.Errorf("expected %q to eq %q", got, expected)
+	}
+}
+
+func TestGoFile_pruneOrphans(t *testing.T) {
+	src := `package p
+
+type User struct{}
+`
+	testSrc := `package p
+
+import (
+	"reflect"
+	"testing"
+)
+
+// TestUser_Add tests Add.
+func TestUser_Add(t *testing.T) {
+	if !reflect.DeepEqual(1, 1) {
+		t.Fatal()
+	}
+}
+
+func TestUser_Delete(t *testing.T) {
+	t.Parallel()
+}
+`
+	goFile, err := parse("p.go", strings.NewReader(src))
+	if err != nil {
+		t.Fatalf("parse failed: %s", err)
+	}
+
+	goTestFile, err := parse("p_test.go", strings.NewReader(testSrc))
+	if err != nil {
+		t.Fatalf("parse failed: %s", err)
+	}
+
+	orphans, err := findOrphans(goTestFile, []*GoFile{goFile}, &diffOpts{})
+	if err != nil {
+		t.Fatalf("findOrphans failed: %s", err)
+	}
+	goTestFile.pruneOrphans(orphans)
+
+	res, err := goTestFile.Generate()
+	if err != nil {
+		t.Fatalf("Generate failed: %s", err)
+	}
+
+	expected := `package p
+
+import (
+	"reflect"
+	"testing"
+)
+
+// TestUser_Add tests Add.
+func TestUser_Add(t *testing.T) {
+	if !reflect.DeepEqual(1, 1) {
+		t.Fatal()
+	}
+}
+`
+	if string(res) != expected {
+		t.Errorf("expected %q to eq %q", res, expected)
+	}
+}
+
+func TestGoFile_pruneOrphans_imports(t *testing.T) {
+	testSrc := `package p
+
+import (
+	"testing"
+)
+
+func TestRemoved(t *testing.T) {
+	t.Parallel()
+}
+
+var  keep = 1 // not gofmt-ed
+`
+	goTestFile, err := parse("p_test.go", strings.NewReader(testSrc))
+	if err != nil {
+		t.Fatalf("parse failed: %s", err)
+	}
+
+	orphans, err := findOrphans(goTestFile, nil, &diffOpts{})
+	if err != nil {
+		t.Fatalf("findOrphans failed: %s", err)
+	}
+	goTestFile.pruneOrphans(orphans)
+
+	res, err := goTestFile.Generate()
+	if err != nil {
+		t.Fatalf("Generate failed: %s", err)
+	}
+
+	expected := "package p\n\nvar  keep = 1 // not gofmt-ed\n"
+	if string(res) != expected {
+		t.Errorf("expected %q to eq %q", res, expected)
+	}
+
+	edits, err := goTestFile.TextEdits()
+	if err != nil {
+		t.Fatalf("TextEdits failed: %s", err)
+	}
+	if len(edits) != 2 {
+		t.Errorf("expected %d to eq %d", len(edits), 2)
+	}
+}
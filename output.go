@@ -0,0 +1,73 @@
+package main
+
+import "bytes"
+
+// outputFile is a file which gotests generates or updates.
+type outputFile interface {
+	// Path returns the path of the file.
+	Path() string
+
+	// Original returns the current content of the file.
+	// It's empty if the file does not exist yet.
+	Original() []byte
+
+	// Generate returns the new content of the file.
+	Generate() ([]byte, error)
+
+	// Changed returns true if the file needs to be written
+	// with the new content res.
+	Changed(res []byte) bool
+}
+
+// Path returns the file name of GoFile.
+func (gf *GoFile) Path() string {
+	return gf.FileName
+}
+
+// Original returns SrcBytes of GoFile.
+func (gf *GoFile) Original() []byte {
+	return gf.SrcBytes
+}
+
+// Changed returns true if res is not the same as SrcBytes.
+func (gf *GoFile) Changed(res []byte) bool {
+	return !bytes.Equal(gf.SrcBytes, res)
+}
+
+// newFile is a non-Go file (e.g., golden file) which gotests creates
+// only when it does not exist. Existing one is never overwritten.
+type newFile struct {
+	path    string
+	content []byte
+	exists  bool
+}
+
+func (f *newFile) Path() string {
+	return f.path
+}
+
+func (f *newFile) Original() []byte {
+	return nil
+}
+
+func (f *newFile) Generate() ([]byte, error) {
+	return f.content, nil
+}
+
+func (f *newFile) Changed(res []byte) bool {
+	return !f.exists
+}
+
+// outputEdits returns the edits which change the original content of
+// outFile to the generated one. It returns nil if it's not changed.
+func outputEdits(outFile outputFile) ([]*edit, error) {
+	if gf, ok := outFile.(*GoFile); ok {
+		return gf.TextEdits()
+	}
+
+	res, err := outFile.Generate()
+	if err != nil || !outFile.Changed(res) {
+		return nil, err
+	}
+	return []*edit{{Offset: 0, End: len(outFile.Original()), Text: res}}, nil
+}
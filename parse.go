@@ -1,10 +1,13 @@
 package main
 
 import (
+	"bytes"
+	"encoding/json"
 	"fmt"
 	"go/ast"
 	"go/parser"
 	"go/token"
+	"go/types"
 	"io"
 	"io/ioutil"
 	"os"
@@ -21,13 +24,13 @@ func parse(filename string, rd io.Reader) (*GoFile, error) {
 	}
 
 	fset := token.NewFileSet()
-	f, err := parser.ParseFile(fset, filename, srcBytes, 0)
+	f, err := parser.ParseFile(fset, filename, srcBytes, parser.ParseComments)
 	if err != nil {
 		return nil, err
 	}
 	DebugAst(fset, f)
 
-	var funcs []string
+	var funcs []*Func
 	var methods []*Method
 	ast.Inspect(f, func(node ast.Node) bool {
 		switch x := node.(type) {
@@ -35,7 +38,10 @@ func parse(filename string, rd io.Reader) (*GoFile, error) {
 			Debugf("FuncDecl: %#v", x.Name)
 			// receiver (methods) or nil (functions)
 			if x.Recv == nil {
-				funcs = append(funcs, x.Name.Name)
+				funcs = append(funcs, &Func{
+					Name: x.Name.Name,
+					Decl: x,
+				})
 				return true
 			}
 
@@ -45,42 +51,162 @@ func parse(filename string, rd io.Reader) (*GoFile, error) {
 				return true
 			}
 
-			field := fields[0]
-			t := field.Type
-			var recvName string
-			switch x2 := t.(type) {
-			case *ast.StarExpr:
-				switch x3 := x2.X.(type) {
-				case *ast.Ident:
-					recvName = x3.Name
-				}
-			case *ast.Ident:
-				recvName = x2.Name
-			default:
+			ident, ok := recvTypeName(fields[0].Type).(*ast.Ident)
+			if !ok {
 				// Should not reach here...
 				return false
 			}
 
-			methods = append(methods, &Method{
-				RecvName: recvName,
+			method := &Method{
+				RecvName: ident.Name,
 				Name:     x.Name.Name,
-			})
+				Decl:     x,
+			}
+
+			methods = append(methods, method)
 		}
 		return true
 	})
 
+	var types []*Type
+	for _, decl := range f.Decls {
+		genDecl, ok := decl.(*ast.GenDecl)
+		if !ok || genDecl.Tok != token.TYPE {
+			continue
+		}
+
+		for _, spec := range genDecl.Specs {
+			typeSpec := spec.(*ast.TypeSpec)
+			types = append(types, &Type{
+				Name: typeSpec.Name.Name,
+				Spec: typeSpec,
+			})
+		}
+	}
+
 	Debugf("Funcs: %#v", methods)
 	Debugf("Methods: %#v", methods)
 
-	return &GoFile{
+	goFile := &GoFile{
 		PackageName: f.Name.Name,
 		FileName:    filename,
 		SrcBytes:    srcBytes,
 		Funcs:       funcs,
 		Methods:     methods,
+		Types:       types,
 		FSet:        fset,
 		AstFile:     f,
-	}, nil
+	}
+
+	// Constraints declared in the other files of package are resolved
+	// by resolveTypeParams.
+	goFile.initSignatures(newTypeDecls(goFile))
+
+	return goFile, nil
+}
+
+// recvTypeName returns the type name of receiver type
+// (e.g., `Stack` for `*Stack[T]`).
+func recvTypeName(recvType ast.Expr) ast.Expr {
+	if star, ok := recvType.(*ast.StarExpr); ok {
+		recvType = star.X
+	}
+	switch x := recvType.(type) {
+	case *ast.IndexExpr:
+		return x.X
+	case *ast.IndexListExpr:
+		return x.X
+	}
+	return recvType
+}
+
+// recvTypeParams returns the type params of receiver of generic type
+// (e.g., `T` for `*Stack[T]`).
+func recvTypeParams(recvType ast.Expr) []ast.Expr {
+	if star, ok := recvType.(*ast.StarExpr); ok {
+		recvType = star.X
+	}
+	switch x := recvType.(type) {
+	case *ast.IndexExpr:
+		return []ast.Expr{x.Index}
+	case *ast.IndexListExpr:
+		return x.Indices
+	}
+	return nil
+}
+
+// initSignatures sets the signatures of functions and methods. Their type
+// params and the ones of receivers are instantiated with the constraints
+// declared in decls (see typeArg). Receivers of generic types which are
+// not in decls are instantiated with any.
+func (gf *GoFile) initSignatures(decls typeDecls) {
+	for _, fun := range gf.Funcs {
+		fun.Signature = NewSignature(fun.Decl.Type, decls)
+	}
+
+	for _, method := range gf.Methods {
+		method.Signature = NewSignature(method.Decl.Type, decls)
+		method.RecvTypeArgs = nil
+
+		typeParams := recvTypeParams(method.Decl.Recv.List[0].Type)
+		if len(typeParams) == 0 {
+			continue
+		}
+
+		// Type params of receiver are named in the type declaration.
+		var names []string
+		declArgs := make(map[string]ast.Expr)
+		if spec, ok := decls[method.RecvName]; ok && spec.TypeParams != nil {
+			names, declArgs = typeArgs(spec.TypeParams, decls)
+		}
+
+		args := make(map[string]ast.Expr)
+		for i, param := range typeParams {
+			var arg ast.Expr = ast.NewIdent("any")
+			if i < len(names) {
+				arg = declArgs[names[i]]
+			}
+
+			if ident, ok := param.(*ast.Ident); ok && ident.Name != "_" {
+				args[ident.Name] = arg
+			}
+			method.RecvTypeArgs = append(method.RecvTypeArgs, types.ExprString(arg))
+		}
+		method.Signature.instantiate(args)
+	}
+}
+
+// hasTypeParams returns true if any function or method (or its receiver)
+// has type params.
+func (gf *GoFile) hasTypeParams() bool {
+	for _, fun := range gf.Funcs {
+		if fun.Decl.Type.TypeParams != nil {
+			return true
+		}
+	}
+	for _, method := range gf.Methods {
+		if len(recvTypeParams(method.Decl.Recv.List[0].Type)) > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// resolveTypeParams instantiates type params of functions and methods
+// with the constraints declared in the package of the file (see typeArg).
+func (gf *GoFile) resolveTypeParams() error {
+	if !gf.hasTypeParams() {
+		return nil
+	}
+
+	pkgFiles, err := parsePackageFiles(filepath.Dir(gf.FileName))
+	if err != nil {
+		return err
+	}
+
+	// The file itself may differ from the one on disk (e.g., -overlay).
+	gf.initSignatures(newTypeDecls(append(pkgFiles, gf)...))
+	return nil
 }
 
 func ParseFile(path string) (*GoFile, error) {
@@ -99,10 +225,84 @@ func ParseFile(path string) (*GoFile, error) {
 	}
 	defer f.Close()
 
-	fi, err := f.Stat()
+	return parse(path, f)
+}
+
+// parseFileOverlay parses the file of path. If overlay has the content
+// of path, it's parsed instead of the file.
+func parseFileOverlay(path string, overlay map[string][]byte) (*GoFile, error) {
+	path, err := filepath.Abs(path)
+	if err != nil {
+		return nil, err
+	}
+
+	if src, ok := overlay[path]; ok {
+		return parse(path, bytes.NewReader(src))
+	}
+	return ParseFile(path)
+}
+
+// readOverlay reads the overlay file in the format of `go build -overlay`
+// (`{"Replace": {"path": "replacement path"}}`) and returns the content
+// of the replacement files keyed by the absolute paths they replace.
+func readOverlay(path string) (map[string][]byte, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var overlay struct {
+		Replace map[string]string
+	}
+	if err := json.Unmarshal(data, &overlay); err != nil {
+		return nil, fmt.Errorf("failed to parse overlay %s: %s", path, err)
+	}
+
+	contents := make(map[string][]byte, len(overlay.Replace))
+	for from, to := range overlay.Replace {
+		from, err := filepath.Abs(from)
+		if err != nil {
+			return nil, err
+		}
+
+		contents[from], err = ioutil.ReadFile(to)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return contents, nil
+}
+
+// parseTestFiles parses all test files in the given directory.
+func parseTestFiles(dir string) ([]*GoFile, error) {
+	return parseFiles(filepath.Join(dir, "*_test.go"), nil)
+}
+
+// parsePackageFiles parses all non-test .go files in the given directory.
+func parsePackageFiles(dir string) ([]*GoFile, error) {
+	return parseFiles(filepath.Join(dir, "*.go"), func(path string) bool {
+		return !strings.HasSuffix(path, "_test.go")
+	})
+}
+
+func parseFiles(pattern string, filter func(path string) bool) ([]*GoFile, error) {
+	paths, err := filepath.Glob(pattern)
 	if err != nil {
 		return nil, err
 	}
 
-	return parse(fi.Name(), f)
+	var goFiles []*GoFile
+	for _, path := range paths {
+		if filter != nil && !filter(path) {
+			continue
+		}
+
+		goFile, err := ParseFile(path)
+		if err != nil {
+			return nil, err
+		}
+		goFiles = append(goFiles, goFile)
+	}
+
+	return goFiles, nil
 }
@@ -1,6 +1,39 @@
 package main
 
-import "testing"
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
 
 func TestParseFile(t *testing.T) {
 }
+
+func TestReadOverlay(t *testing.T) {
+	dir, err := ioutil.TempDir("", Name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	buf := filepath.Join(dir, "buf.go")
+	if err := ioutil.WriteFile(buf, []byte("package p\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	overlay := filepath.Join(dir, "overlay.json")
+	data := `{"Replace": {"` + filepath.ToSlash(filepath.Join(dir, "p.go")) + `": "` + filepath.ToSlash(buf) + `"}}`
+	if err := ioutil.WriteFile(overlay, []byte(data), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	contents, err := readOverlay(overlay)
+	if err != nil {
+		t.Fatalf("readOverlay failed: %s", err)
+	}
+
+	if got := string(contents[filepath.Join(dir, "p.go")]); got != "package p\n" {
+		t.Errorf("expected %q to eq %q", got, "package p\n")
+	}
+}
@@ -0,0 +1,395 @@
+package main
+
+import (
+	"fmt"
+	"go/ast"
+	"go/token"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// Rename is renaming of target function or method.
+type Rename struct {
+	// Old and New are the names of target. RecvName is empty
+	// for functions.
+	Old, New *Method
+}
+
+// ParseRenames parses comma separated renames like
+// `Add=Insert,User.Get=User.Find`.
+func ParseRenames(s string) ([]*Rename, error) {
+	if s == "" {
+		return nil, nil
+	}
+
+	var renames []*Rename
+	for _, pair := range strings.Split(s, ",") {
+		names := strings.Split(pair, "=")
+		if len(names) != 2 {
+			return nil, fmt.Errorf("invalid rename %q (must be OLD=NEW)", pair)
+		}
+
+		r := &Rename{Old: parseTargetName(names[0]), New: parseTargetName(names[1])}
+		if r.Old.Name == "" || r.New.Name == "" || (r.Old.RecvName == "") != (r.New.RecvName == "") {
+			return nil, fmt.Errorf("invalid rename %q (must be Func=Func or Type.Method=Type.Method)", pair)
+		}
+
+		renames = append(renames, r)
+	}
+
+	return renames, nil
+}
+
+// parseTargetName parses function name (`Add`) or method
+// name (`User.Add`).
+func parseTargetName(s string) *Method {
+	s = strings.TrimSpace(s)
+	if i := strings.Index(s, "."); i >= 0 {
+		return &Method{RecvName: s[:i], Name: s[i+1:]}
+	}
+	return &Method{Name: s}
+}
+
+// fullName returns the name of target used in messages (e.g., `User.Add`).
+func fullName(m *Method) string {
+	if m.RecvName != "" {
+		return m.RecvName + "." + m.Name
+	}
+	return m.Name
+}
+
+// renameTests renames testing functions (tests, benchmarks, examples
+// and fuzz tests) of the renamed targets in place. References of the
+// testing functions are renamed in the whole file, and calls of target
+// and its names in string literals (test case names, and qualified names
+// in failure messages) are renamed in the testing functions and their doc
+// comments. Testing functions are not renamed if the new ones already exist.
+func (gf *GoFile) renameTests(renames []*Rename, opts *diffOpts) error {
+	opts.init()
+
+	// Function and method name templates of each kind
+	tmplPairs := [][2]string{
+		{opts.ExpectTestFuncTmpl, opts.ExpectTestFuncMethodTmpl},
+		{defaultExpectBenchmarkFuncTmpl, defaultExpectBenchmarkFuncMethodTmpl},
+		{defaultExpectExampleFuncTmpl, defaultExpectExampleFuncMethodTmpl},
+		{defaultExpectFuzzFuncTmpl, defaultExpectFuzzFuncMethodTmpl},
+	}
+
+	for _, r := range renames {
+		for _, tmpls := range tmplPairs {
+			tmpl := tmpls[0]
+			if r.Old.RecvName != "" {
+				tmpl = tmpls[1]
+			}
+
+			oldName, err := execFuncTmpl(tmpl, r.Old)
+			if err != nil {
+				return err
+			}
+
+			newName, err := execFuncTmpl(tmpl, r.New)
+			if err != nil {
+				return err
+			}
+
+			decl := gf.funcDecl(oldName)
+			if decl == nil || gf.funcDecl(newName) != nil {
+				continue
+			}
+
+			gf.renameFunc(decl, r, newName)
+		}
+	}
+
+	return nil
+}
+
+// renameFunc renames testing function decl to newName.
+func (gf *GoFile) renameFunc(decl *ast.FuncDecl, r *Rename, newName string) {
+	oldName := decl.Name.Name
+
+	renamed := make(map[token.Pos]bool)
+	renameIdent := func(ident *ast.Ident, name string) {
+		if renamed[ident.Pos()] {
+			return
+		}
+		renamed[ident.Pos()] = true
+
+		gf.edits = append(gf.edits, &edit{
+			Offset: gf.FSet.Position(ident.Pos()).Offset,
+			End:    gf.FSet.Position(ident.End()).Offset,
+			Text:   []byte(name),
+		})
+	}
+
+	// References of the testing function
+	ast.Inspect(gf.AstFile, func(node ast.Node) bool {
+		if ident, ok := node.(*ast.Ident); ok && ident.Name == oldName {
+			renameIdent(ident, newName)
+		}
+		return true
+	})
+
+	// References of target and names in string literals. Selected names
+	// are renamed only if they are selected from the values of the
+	// receiver type, and the bare names in string literals are renamed
+	// only in test case names and t.Run names.
+	isMethod := r.Old.RecvName != ""
+	recvs := gf.receiverNames(decl, r.Old.RecvName)
+	caseNames := caseNameLits(decl)
+	replaceFull, replaceName := nameReplacers(r)
+	notFunc := make(map[*ast.Ident]bool)
+	ast.Inspect(decl.Body, func(node ast.Node) bool {
+		switch x := node.(type) {
+		case *ast.SelectorExpr:
+			// Selected name (e.g., `u.Add` or `pkg.Add`) is not the function.
+			notFunc[x.Sel] = true
+			if isMethod && x.Sel.Name == r.Old.Name && isReceiver(x.X, r.Old.RecvName, recvs) {
+				renameIdent(x.Sel, r.New.Name)
+			}
+		case *ast.KeyValueExpr:
+			if key, ok := x.Key.(*ast.Ident); ok {
+				notFunc[key] = true
+			}
+		case *ast.Ident:
+			if !isMethod && !notFunc[x] && x.Name == r.Old.Name {
+				renameIdent(x, r.New.Name)
+			}
+		case *ast.BasicLit:
+			if x.Kind != token.STRING {
+				break
+			}
+			s, err := strconv.Unquote(x.Value)
+			if err != nil {
+				break
+			}
+			replaced := replaceFull(s)
+			if caseNames[x] {
+				replaced = replaceName(replaced)
+			}
+			if replaced != s {
+				gf.edits = append(gf.edits, &edit{
+					Offset: gf.FSet.Position(x.Pos()).Offset,
+					End:    gf.FSet.Position(x.End()).Offset,
+					Text:   []byte(requote(x.Value, replaced)),
+				})
+			}
+		}
+		return true
+	})
+
+	// Doc comment: its leading identifier (the name of the testing
+	// function, or target whose synopsis is copied by -doc) and the
+	// full names of target.
+	if decl.Doc != nil {
+		docNames := map[string]string{
+			oldName:         newName,
+			fullName(r.Old): fullName(r.New),
+			r.Old.Name:      r.New.Name,
+		}
+		for i, c := range decl.Doc.List {
+			text := replaceFull(c.Text)
+			if i == 0 {
+				text = renameDocIdent(text, docNames)
+			}
+			if text != c.Text {
+				gf.edits = append(gf.edits, &edit{
+					Offset: gf.FSet.Position(c.Pos()).Offset,
+					End:    gf.FSet.Position(c.End()).Offset,
+					Text:   []byte(text),
+				})
+			}
+		}
+	}
+
+	decl.Name.Name = newName
+	for _, fun := range gf.Funcs {
+		if fun.Decl == decl {
+			fun.Name = newName
+		}
+	}
+}
+
+// renameDocIdent renames the leading identifier of line comment text
+// (e.g., `TestAdd` of `// TestAdd tests Add.`) if it's in names.
+func renameDocIdent(text string, names map[string]string) string {
+	body := strings.TrimPrefix(text, "//")
+	if body == text {
+		return text
+	}
+
+	trimmed := strings.TrimLeft(body, " \t")
+	end := strings.IndexFunc(trimmed, func(r rune) bool {
+		return r != '_' && r != '.' && !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	if end < 0 {
+		end = len(trimmed)
+	}
+
+	// The period ending the sentence is not a part of it.
+	ident := strings.TrimSuffix(trimmed[:end], ".")
+	newIdent, ok := names[ident]
+	if !ok {
+		return text
+	}
+	return text[:len(text)-len(trimmed)] + newIdent + trimmed[len(ident):]
+}
+
+// receiverNames returns the names of the variables, parameters and
+// struct fields (e.g., the fields of test tables) in decl whose values
+// are of the receiver type recvName: declared with the type, or assigned
+// from its composite literal or new.
+func (gf *GoFile) receiverNames(decl *ast.FuncDecl, recvName string) map[string]bool {
+	names := make(map[string]bool)
+	if recvName == "" {
+		return names
+	}
+
+	ast.Inspect(decl, func(node ast.Node) bool {
+		switch x := node.(type) {
+		case *ast.Field:
+			if isRecvType(x.Type, recvName) {
+				for _, name := range x.Names {
+					names[name.Name] = true
+				}
+			}
+		case *ast.ValueSpec:
+			for i, name := range x.Names {
+				if (x.Type != nil && isRecvType(x.Type, recvName)) ||
+					(i < len(x.Values) && isRecvValue(x.Values[i], recvName)) {
+					names[name.Name] = true
+				}
+			}
+		case *ast.AssignStmt:
+			if len(x.Lhs) != len(x.Rhs) {
+				break
+			}
+			for i, lhs := range x.Lhs {
+				if ident, ok := lhs.(*ast.Ident); ok && isRecvValue(x.Rhs[i], recvName) {
+					names[ident.Name] = true
+				}
+			}
+		}
+		return true
+	})
+
+	return names
+}
+
+// isReceiver reports whether expr (selected from) is a value of the receiver
+// type recvName: a variable or a field in names, a composite literal or
+// new of the type, or the type itself (method expressions).
+func isReceiver(expr ast.Expr, recvName string, names map[string]bool) bool {
+	switch x := expr.(type) {
+	case *ast.Ident:
+		return names[x.Name] || x.Name == recvName
+	case *ast.SelectorExpr:
+		// Fields (e.g., `tt.u`) or the type of other package (`p.User`)
+		return names[x.Sel.Name] || x.Sel.Name == recvName
+	case *ast.ParenExpr:
+		return isReceiver(x.X, recvName, names)
+	case *ast.StarExpr:
+		return isRecvType(x.X, recvName)
+	}
+	return isRecvValue(expr, recvName)
+}
+
+// isRecvValue reports whether expr is a new value of the receiver type
+// recvName (e.g., `User{}`, `&User{}` or `new(User)`).
+func isRecvValue(expr ast.Expr, recvName string) bool {
+	switch x := expr.(type) {
+	case *ast.ParenExpr:
+		return isRecvValue(x.X, recvName)
+	case *ast.UnaryExpr:
+		return x.Op == token.AND && isRecvValue(x.X, recvName)
+	case *ast.CompositeLit:
+		return x.Type != nil && isRecvType(x.Type, recvName)
+	case *ast.CallExpr:
+		fun, ok := x.Fun.(*ast.Ident)
+		return ok && fun.Name == "new" && len(x.Args) == 1 && isRecvType(x.Args[0], recvName)
+	}
+	return false
+}
+
+// isRecvType reports whether expr is the receiver type recvName or its
+// pointer (e.g., `User`, `*User`, `p.User` or `User[int]`).
+func isRecvType(expr ast.Expr, recvName string) bool {
+	switch x := expr.(type) {
+	case *ast.Ident:
+		return x.Name == recvName
+	case *ast.SelectorExpr:
+		return x.Sel.Name == recvName
+	case *ast.ParenExpr:
+		return isRecvType(x.X, recvName)
+	case *ast.StarExpr:
+		return isRecvType(x.X, recvName)
+	case *ast.IndexExpr:
+		return isRecvType(x.X, recvName)
+	case *ast.IndexListExpr:
+		return isRecvType(x.X, recvName)
+	}
+	return false
+}
+
+// caseNameLits returns the string literals of test case names in decl:
+// the values of `name` keys in composite literals (test tables) and the
+// names of subtests (e.g., `t.Run("name", ...)`).
+func caseNameLits(decl *ast.FuncDecl) map[*ast.BasicLit]bool {
+	lits := make(map[*ast.BasicLit]bool)
+	ast.Inspect(decl, func(node ast.Node) bool {
+		switch x := node.(type) {
+		case *ast.KeyValueExpr:
+			key, ok := x.Key.(*ast.Ident)
+			lit, isLit := x.Value.(*ast.BasicLit)
+			if ok && isLit && strings.EqualFold(key.Name, "name") {
+				lits[lit] = true
+			}
+		case *ast.CallExpr:
+			sel, ok := x.Fun.(*ast.SelectorExpr)
+			if !ok || sel.Sel.Name != "Run" || len(x.Args) == 0 {
+				break
+			}
+			if lit, ok := x.Args[0].(*ast.BasicLit); ok {
+				lits[lit] = true
+			}
+		}
+		return true
+	})
+	return lits
+}
+
+// nameReplacers returns the functions which replace old names of target
+// in s with new ones: the full name (e.g., `User.Add`, or `Add(` for
+// functions), and the name (`Add`) if it's changed. The latter is used
+// only for test case names, since the bare name can be a part of other
+// texts.
+func nameReplacers(r *Rename) (func(s string) string, func(s string) string) {
+	fullRe := regexp.MustCompile(`\b` + regexp.QuoteMeta(fullName(r.Old)) + `\b`)
+	newFull := fullName(r.New)
+	if r.Old.RecvName == "" {
+		fullRe = regexp.MustCompile(`\b` + regexp.QuoteMeta(r.Old.Name) + `\(`)
+		newFull += "("
+	}
+	nameRe := regexp.MustCompile(`\b` + regexp.QuoteMeta(r.Old.Name) + `\b`)
+
+	replaceFull := func(s string) string {
+		return fullRe.ReplaceAllLiteralString(s, newFull)
+	}
+	replaceName := func(s string) string {
+		if r.Old.Name == r.New.Name {
+			return s
+		}
+		return nameRe.ReplaceAllLiteralString(s, r.New.Name)
+	}
+	return replaceFull, replaceName
+}
+
+// requote quotes s in the same way as the original literal.
+func requote(lit, s string) string {
+	if strings.HasPrefix(lit, "`") && !strings.Contains(s, "`") {
+		return "`" + s + "`"
+	}
+	return strconv.Quote(s)
+}
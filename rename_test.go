@@ -0,0 +1,176 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParseRenames(t *testing.T) {
+	renames, err := ParseRenames("Add=Insert, User.Get=User.Find")
+	if err != nil {
+		t.Fatalf("ParseRenames failed: %s", err)
+	}
+
+	var res []string
+	for _, r := range renames {
+		res = append(res, fullName(r.Old)+"="+fullName(r.New))
+	}
+
+	expected := "Add=Insert,User.Get=User.Find"
+	if got := strings.Join(res, ","); got != expected {
+		t.Errorf("expected %q to eq %q", got, expected)
+	}
+
+	for _, s := range []string{"Add", "Add=User.Insert", "=Insert"} {
+		if _, err := ParseRenames(s); err == nil {
+			t.Errorf("expected %q to be invalid", s)
+		}
+	}
+}
+
+func TestGoFile_renameTests(t *testing.T) {
+	src := `package p
+
+import (
+	"sync"
+	"testing"
+	"time"
+)
+
+// TestUser_Add tests User.Add.
+func TestUser_Add(t *testing.T) {
+	u := &User{}
+	t.Run("Add twice", func(t *testing.T) {
+		u.Add("a")
+		u.Add("a")
+		if u.Len() != 1 {
+			t.Errorf("User.Add() added twice")
+		}
+	})
+
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		if new(User).Add("b"); u.Len() != 1 {
+			t.Errorf("Address Add changed")
+		}
+	}()
+	wg.Wait()
+
+	tests := []struct {
+		name string
+		user *User
+		now  time.Time
+	}{
+		{name: "Add one", user: &User{}, now: time.Now()},
+	}
+	for _, tt := range tests {
+		tt.user.Add("c")
+		_ = tt.now.Add
+	}
+}
+
+func BenchmarkUser_Add(b *testing.B) {
+	var u User
+	for i := 0; i < b.N; i++ {
+		u.Add("a")
+	}
+}
+
+func TestAll(t *testing.T) {
+	TestUser_Add(t)
+}
+`
+	goTestFile, err := parse("p_test.go", strings.NewReader(src))
+	if err != nil {
+		t.Fatalf("parse failed: %s", err)
+	}
+
+	renames, err := ParseRenames("User.Add=User.Insert")
+	if err != nil {
+		t.Fatalf("ParseRenames failed: %s", err)
+	}
+
+	if err := goTestFile.renameTests(renames, &diffOpts{}); err != nil {
+		t.Fatalf("renameTests failed: %s", err)
+	}
+
+	res, err := goTestFile.Generate()
+	if err != nil {
+		t.Fatalf("Generate failed: %s", err)
+	}
+
+	expected := strings.NewReplacer(
+		"// TestUser_Add tests User.Add.", "// TestUser_Insert tests User.Insert.",
+		"TestUser_Add(", "TestUser_Insert(",
+		"BenchmarkUser_Add(", "BenchmarkUser_Insert(",
+		"u.Add(", "u.Insert(",
+		"new(User).Add(", "new(User).Insert(",
+		"tt.user.Add(", "tt.user.Insert(",
+		`"Add twice"`, `"Insert twice"`,
+		`"Add one"`, `"Insert one"`,
+		`"User.Add() added twice"`, `"User.Insert() added twice"`,
+	).Replace(src)
+	if string(res) != expected {
+		t.Errorf("expected %q to eq %q", res, expected)
+	}
+
+	for _, name := range []string{"TestUser_Insert", "BenchmarkUser_Insert"} {
+		if exist, _ := goTestFile.hasTestFunc(name, &diffOpts{}); !exist {
+			t.Errorf("expected %s to exist", name)
+		}
+	}
+}
+
+func TestGoFile_renameTests_func(t *testing.T) {
+	src := `package p
+
+import "testing"
+
+// Add returns n.
+// Address is not renamed.
+func TestAdd(t *testing.T) {
+	tests := []struct {
+		name string
+		want int
+	}{
+		{name: "Add zero", want: 0},
+	}
+	for _, tt := range tests {
+		if got := Add(tt.want); got != tt.want {
+			t.Errorf("Add() = %v, want %v (Address Add)", got, tt.want)
+		}
+	}
+}
+`
+	goTestFile, err := parse("p_test.go", strings.NewReader(src))
+	if err != nil {
+		t.Fatalf("parse failed: %s", err)
+	}
+
+	renames, err := ParseRenames("Add=Insert")
+	if err != nil {
+		t.Fatalf("ParseRenames failed: %s", err)
+	}
+
+	if err := goTestFile.renameTests(renames, &diffOpts{}); err != nil {
+		t.Fatalf("renameTests failed: %s", err)
+	}
+
+	res, err := goTestFile.Generate()
+	if err != nil {
+		t.Fatalf("Generate failed: %s", err)
+	}
+
+	expected := strings.NewReplacer(
+		"// Add returns n.", "// Insert returns n.",
+		"TestAdd(", "TestInsert(",
+		`"Add zero"`, `"Insert zero"`,
+		"got := Add(", "got := Insert(",
+		`"Add() = `, `"Insert() = `,
+	).Replace(src)
+	if string(res) != expected {
+		t.Errorf("expected %q to eq %q", res, expected)
+	}
+}
@@ -0,0 +1,368 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"go/ast"
+	"html/template"
+	"io"
+	"io/ioutil"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Statuses of report entries.
+const (
+	statusTested   = "tested"
+	statusMissing  = "missing"
+	statusOrphaned = "orphaned"
+)
+
+// report is the report of missing tests (see -report). Its JSON can
+// be given to -baseline of the next report to compare with.
+type report struct {
+	GeneratedAt time.Time        `json:"generated_at"`
+	Mode        string           `json:"mode"`
+	Packages    []*reportPackage `json:"packages"`
+	Total       reportCounts     `json:"total"`
+
+	// Baseline is the total of the baseline report generated
+	// at BaselineAt.
+	Baseline   *reportCounts `json:"-"`
+	BaselineAt time.Time     `json:"-"`
+
+	// link is the template of links to source lines (see SourceLink).
+	link string
+}
+
+// reportCounts are the counts of statuses of report entries.
+type reportCounts struct {
+	Tested   int `json:"tested"`
+	Missing  int `json:"missing"`
+	Orphaned int `json:"orphaned"`
+
+	// Coverage is the percentage of tested functions and methods.
+	Coverage float64 `json:"coverage"`
+}
+
+func (c *reportCounts) count(status string) {
+	switch status {
+	case statusTested:
+		c.Tested++
+	case statusMissing:
+		c.Missing++
+	case statusOrphaned:
+		c.Orphaned++
+	}
+
+	c.Coverage = 100
+	if n := c.Tested + c.Missing; n > 0 {
+		c.Coverage = float64(c.Tested) * 100 / float64(n)
+	}
+}
+
+// reportPackage is the report of source files in a directory.
+type reportPackage struct {
+	Dir   string        `json:"dir"`
+	Name  string        `json:"name"`
+	Files []*reportFile `json:"files"`
+	reportCounts
+
+	// Baseline is the counts of the package in the baseline report.
+	Baseline *reportCounts `json:"-"`
+}
+
+// reportFile is the report of a source file.
+type reportFile struct {
+	Path    string         `json:"path"`
+	Entries []*reportEntry `json:"entries"`
+}
+
+// reportEntry is a function or method of a source file, or an orphaned
+// test of its test file.
+type reportEntry struct {
+	// Name is the name of the function or method (e.g., `User.Add`).
+	Name string `json:"name"`
+
+	// Test is the name of the (expected) test.
+	Test   string `json:"test"`
+	Status string `json:"status"`
+
+	// Path and Line are the declaration of the function or method
+	// (or the orphaned test).
+	Path string `json:"path"`
+	Line int    `json:"line"`
+}
+
+func newReport(mode Mode, link string) *report {
+	return &report{
+		GeneratedAt: time.Now(),
+		Mode:        mode.String(),
+		Packages:    []*reportPackage{},
+		Total:       reportCounts{Coverage: 100},
+		link:        link,
+	}
+}
+
+// add adds the report of a source file in dir which declares
+// the package of name.
+func (r *report) add(dir, name string, f *reportFile) {
+	var pkg *reportPackage
+	for _, p := range r.Packages {
+		if p.Dir == dir && p.Name == name {
+			pkg = p
+		}
+	}
+	if pkg == nil {
+		pkg = &reportPackage{Dir: dir, Name: name, reportCounts: reportCounts{Coverage: 100}}
+		r.Packages = append(r.Packages, pkg)
+	}
+
+	pkg.Files = append(pkg.Files, f)
+	for _, e := range f.Entries {
+		pkg.count(e.Status)
+		r.Total.count(e.Status)
+	}
+}
+
+// readBaseline reads the JSON report of path and sets the counts of
+// its packages and total as the baselines to compare with.
+func (r *report) readBaseline(path string) error {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return err
+	}
+
+	var baseline report
+	if err := json.Unmarshal(data, &baseline); err != nil {
+		return fmt.Errorf("failed to parse baseline %s: %s", path, err)
+	}
+
+	r.Baseline = &baseline.Total
+	r.BaselineAt = baseline.GeneratedAt
+	for _, pkg := range r.Packages {
+		for _, b := range baseline.Packages {
+			if pkg.Dir == b.Dir && pkg.Name == b.Name {
+				counts := b.reportCounts
+				pkg.Baseline = &counts
+			}
+		}
+	}
+	return nil
+}
+
+// write writes the report in format ("html" or "json").
+func (r *report) write(w io.Writer, format string) error {
+	switch format {
+	case "html":
+		return reportTmpl.Execute(w, r)
+	case "json":
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(r)
+	default:
+		return fmt.Errorf("unknown report format: %s", format)
+	}
+}
+
+// SourceLink returns the link to the line of the file of path.
+// `{path}` and `{line}` in the link template are replaced.
+func (r *report) SourceLink(path string, line int) string {
+	return strings.NewReplacer(
+		"{path}", filepath.ToSlash(path),
+		"{line}", strconv.Itoa(line),
+	).Replace(r.link)
+}
+
+// reportFileEntries returns the report of the functions and methods of
+// the source file and the orphaned tests of the test file.
+func reportFileEntries(srcPath, testPath string, opts *generateOpts) (*reportFile, string, error) {
+	goFile, err := parseFileOverlay(srcPath, opts.overlay)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to parse go file: %w", err)
+	}
+	opts.filterChanged(srcPath, goFile)
+
+	goTestFile, err := openTestFile(testPath, goFile.PackageName, opts.overlay)
+	if err != nil {
+		return nil, "", err
+	}
+
+	diffOpts := opts.diffOpts
+	diffOpts.init()
+
+	diffFuncs, err := goFile.diffFuncs(goTestFile, diffOpts)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to diff source file and test file: %s", err)
+	}
+
+	diffMethods, err := goFile.diffMethods(goTestFile, diffOpts)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to diff source file and test file: %s", err)
+	}
+
+	src, err := fmtPath(srcPath)
+	if err != nil {
+		return nil, "", err
+	}
+
+	f := &reportFile{Path: src, Entries: []*reportEntry{}}
+	entry := func(name, test string, missing bool, decl *ast.FuncDecl) {
+		status := statusTested
+		if missing {
+			status = statusMissing
+		}
+		f.Entries = append(f.Entries, &reportEntry{
+			Name:   name,
+			Test:   test,
+			Status: status,
+			Path:   src,
+			Line:   goFile.FSet.Position(decl.Pos()).Line,
+		})
+	}
+
+	for _, fun := range goFile.Funcs {
+		if contains(diffOpts.IgnoreFuncs, fun.Name) ||
+			(!diffOpts.IncludeUnexported && isUnExported(fun.Name)) {
+			continue
+		}
+
+		test, err := execFuncTmpl(diffOpts.ExpectTestFuncTmpl, fun)
+		if err != nil {
+			return nil, "", err
+		}
+		entry(fun.Name, test, containsFunc(diffFuncs, fun), fun.Decl)
+	}
+
+	for _, method := range goFile.Methods {
+		if !diffOpts.IncludeUnexported && isUnExported(method.Name) {
+			continue
+		}
+
+		test, err := execFuncTmpl(diffOpts.ExpectTestFuncMethodTmpl, method)
+		if err != nil {
+			return nil, "", err
+		}
+		entry(method.RecvName+"."+method.Name, test, containsMethod(diffMethods, method), method.Decl)
+	}
+
+	if len(goTestFile.SrcBytes) > 0 {
+		orphans, err := packageOrphans(srcPath, goTestFile, opts)
+		if err != nil {
+			return nil, "", fmt.Errorf("failed to find orphans: %w", err)
+		}
+
+		test, err := fmtPath(testPath)
+		if err != nil {
+			return nil, "", err
+		}
+
+		for _, orphan := range orphans {
+			f.Entries = append(f.Entries, &reportEntry{
+				Name:   orphan.Target,
+				Test:   orphan.Name(),
+				Status: statusOrphaned,
+				Path:   test,
+				Line:   goTestFile.FSet.Position(orphan.Decl.Pos()).Line,
+			})
+		}
+	}
+
+	return f, goFile.PackageName, nil
+}
+
+func containsFunc(funcs []*Func, fun *Func) bool {
+	for _, f := range funcs {
+		if f == fun {
+			return true
+		}
+	}
+	return false
+}
+
+func containsMethod(methods []*Method, method *Method) bool {
+	for _, m := range methods {
+		if m == method {
+			return true
+		}
+	}
+	return false
+}
+
+// processReport adds the report of the source file to opts.report.
+func (cli *CLI) processReport(srcPath, testPath string, opts *generateOpts) int {
+	f, name, err := reportFileEntries(srcPath, testPath, opts)
+	if err != nil {
+		cli.reportError(srcPath, err)
+		return ExitCodeError
+	}
+
+	dir, err := fmtPath(filepath.Dir(srcPath))
+	if err != nil {
+		cli.reportError(srcPath, err)
+		return ExitCodeError
+	}
+
+	opts.report.add(dir, name, f)
+	return ExitCodeOK
+}
+
+var reportFuncs = template.FuncMap{
+	// delta returns the change of coverage from baseline.
+	"delta": func(coverage float64, baseline *reportCounts) string {
+		if baseline == nil {
+			return "new"
+		}
+		return fmt.Sprintf("%+.1f%%", coverage-baseline.Coverage)
+	},
+}
+
+var reportTmpl = template.Must(template.New("report").Funcs(reportFuncs).Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>gotests report</title>
+<style>
+body { font-family: sans-serif; margin: 2em; }
+table { border-collapse: collapse; margin-bottom: 1em; }
+th, td { border: 1px solid #ddd; padding: 4px 8px; text-align: left; }
+td.num { text-align: right; }
+.tested { color: #1a7f37; }
+.missing { color: #cf222e; }
+.orphaned { color: #9a6700; }
+details { margin: 1em 0; }
+summary { font-weight: bold; cursor: pointer; }
+</style>
+</head>
+<body>
+<h1>gotests report</h1>
+<p>Generated at {{ .GeneratedAt.Format "2006-01-02 15:04:05" }}. Tests are found by {{ .Mode }} mode.
+{{- if .Baseline }} Trend is compared with the report generated at {{ .BaselineAt.Format "2006-01-02 15:04:05" }}.{{ end }}</p>
+
+<table>
+<tr><th>Package</th><th>Tested</th><th>Missing</th><th>Orphaned</th><th>Coverage</th>{{ if .Baseline }}<th>Trend</th>{{ end }}</tr>
+{{- range .Packages }}
+<tr><td><a href="#pkg-{{ .Dir }}">{{ .Dir }} ({{ .Name }})</a></td><td class="num">{{ .Tested }}</td><td class="num">{{ .Missing }}</td><td class="num">{{ .Orphaned }}</td><td class="num">{{ printf "%.1f%%" .Coverage }}</td>{{ if $.Baseline }}<td class="num">{{ delta .Coverage .Baseline }}</td>{{ end }}</tr>
+{{- end }}
+<tr><th>Total</th><th class="num">{{ .Total.Tested }}</th><th class="num">{{ .Total.Missing }}</th><th class="num">{{ .Total.Orphaned }}</th><th class="num">{{ printf "%.1f%%" .Total.Coverage }}</th>{{ if .Baseline }}<th class="num">{{ delta .Total.Coverage .Baseline }}</th>{{ end }}</tr>
+</table>
+{{- range .Packages }}
+
+<details id="pkg-{{ .Dir }}" open>
+<summary>{{ .Dir }} ({{ .Name }}) {{ printf "%.1f%%" .Coverage }}</summary>
+{{- range .Files }}
+<h3>{{ .Path }}</h3>
+<table>
+<tr><th>Function</th><th>Test</th><th>Status</th></tr>
+{{- range .Entries }}
+<tr><td><a href="{{ $.SourceLink .Path .Line }}">{{ .Name }}</a></td><td>{{ .Test }}</td><td class="{{ .Status }}">{{ .Status }}</td></tr>
+{{- end }}
+</table>
+{{- end }}
+</details>
+{{- end }}
+</body>
+</html>
+`))
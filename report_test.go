@@ -0,0 +1,120 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestReport(t *testing.T) {
+	dir, err := ioutil.TempDir("", Name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	baseline := newReport(Strict, "{path}#L{line}")
+	baseline.add("p", "p", &reportFile{
+		Path: "p/p.go",
+		Entries: []*reportEntry{
+			{Name: "Add", Test: "TestAdd", Status: statusTested, Path: "p/p.go", Line: 3},
+			{Name: "Sub", Test: "TestSub", Status: statusMissing, Path: "p/p.go", Line: 5},
+		},
+	})
+
+	var buf bytes.Buffer
+	if err := baseline.write(&buf, "json"); err != nil {
+		t.Fatalf("write failed: %s", err)
+	}
+	baselinePath := filepath.Join(dir, "baseline.json")
+	if err := ioutil.WriteFile(baselinePath, buf.Bytes(), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	r := newReport(Strict, "https://example.com/{path}#L{line}")
+	r.add("p", "p", &reportFile{
+		Path: "p/p.go",
+		Entries: []*reportEntry{
+			{Name: "Add", Test: "TestAdd", Status: statusTested, Path: "p/p.go", Line: 3},
+			{Name: "Sub", Test: "TestSub", Status: statusTested, Path: "p/p.go", Line: 5},
+			{Name: "User.Add", Test: "TestUser_Add", Status: statusOrphaned, Path: "p/p_test.go", Line: 9},
+		},
+	})
+	r.add("q", "q", &reportFile{
+		Path:    "q/q.go",
+		Entries: []*reportEntry{{Name: "Mul", Test: "TestMul", Status: statusMissing, Path: "q/q.go", Line: 3}},
+	})
+
+	expected := reportCounts{Tested: 2, Missing: 1, Orphaned: 1, Coverage: float64(2) * 100 / 3}
+	if r.Total != expected {
+		t.Errorf("expected %+v to eq %+v", r.Total, expected)
+	}
+
+	if err := r.readBaseline(baselinePath); err != nil {
+		t.Fatalf("readBaseline failed: %s", err)
+	}
+	if r.Packages[0].Baseline == nil || r.Packages[0].Baseline.Coverage != 50 || r.Packages[1].Baseline != nil {
+		t.Errorf("expected baseline of p only: %+v, %+v", r.Packages[0].Baseline, r.Packages[1].Baseline)
+	}
+
+	buf.Reset()
+	if err := r.write(&buf, "html"); err != nil {
+		t.Fatalf("write failed: %s", err)
+	}
+
+	html := buf.String()
+	for _, s := range []string{
+		`<td class="num">100.0%</td><td class="num">&#43;50.0%</td>`,
+		`<td class="num">0.0%</td><td class="num">new</td>`,
+		`<a href="https://example.com/p/p_test.go#L9">User.Add</a></td><td>TestUser_Add</td><td class="orphaned">orphaned</td>`,
+	} {
+		if !strings.Contains(html, s) {
+			t.Errorf("expected %q to contain %q", html, s)
+		}
+	}
+}
+
+func TestReportFileEntries(t *testing.T) {
+	dir, err := ioutil.TempDir("", Name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	src := "package p\n\nfunc Add() {}\n\nfunc Sub() {}\n"
+	testSrc := "package p\n\nimport \"testing\"\n\nfunc TestAdd(t *testing.T) {}\n\nfunc TestMul(t *testing.T) {\n\tt.Log()\n}\n"
+	srcPath := filepath.Join(dir, "p.go")
+	testPath := filepath.Join(dir, "p_test.go")
+	if err := ioutil.WriteFile(srcPath, []byte(src), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(testPath, []byte(testSrc), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	f, name, err := reportFileEntries(srcPath, testPath, &generateOpts{diffOpts: &diffOpts{}})
+	if err != nil {
+		t.Fatalf("reportFileEntries failed: %s", err)
+	}
+	if name != "p" {
+		t.Errorf("expected %q to eq %q", name, "p")
+	}
+
+	var res []string
+	for _, e := range f.Entries {
+		res = append(res, fmt.Sprintf("%s:%d: %s %s %s", filepath.Base(e.Path), e.Line, e.Name, e.Test, e.Status))
+	}
+
+	expected := strings.Join([]string{
+		"p.go:3: Add TestAdd tested",
+		"p.go:5: Sub TestSub missing",
+		"p_test.go:7: Mul TestMul orphaned",
+	}, "\n")
+	if got := strings.Join(res, "\n"); got != expected {
+		t.Errorf("expected %q to eq %q", got, expected)
+	}
+}
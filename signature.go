@@ -0,0 +1,304 @@
+package main
+
+import (
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/types"
+	"strings"
+
+	"golang.org/x/tools/go/ast/astutil"
+)
+
+// Signature is parameters and results of function or method.
+type Signature struct {
+	Params  []*Field
+	Results []*Field
+
+	// TypeArgs are type arguments to instantiate generic function in
+	// generated code (see typeArg). Type parameters in Params and
+	// Results are replaced with them.
+	TypeArgs []string
+}
+
+// Field is a parameter or a result of function. Name is empty
+// when it's not named in source.
+type Field struct {
+	Name string
+	Type ast.Expr
+}
+
+// TypeString returns the type of the field as it's written in source.
+func (f *Field) TypeString() string {
+	return types.ExprString(f.Type)
+}
+
+// IsVariadic returns true if the field is variadic param
+// (e.g., `opts ...Option`).
+func (f *Field) IsVariadic() bool {
+	_, ok := f.Type.(*ast.Ellipsis)
+	return ok
+}
+
+// VarType returns the type of variable holding the field value.
+// It's slice for variadic param.
+func (f *Field) VarType() string {
+	if ellipsis, ok := f.Type.(*ast.Ellipsis); ok {
+		return "[]" + types.ExprString(ellipsis.Elt)
+	}
+	return f.TypeString()
+}
+
+// NewSignature creates Signature from function type. Type parameters
+// are instantiated with the constraints declared in decls (see typeArg).
+func NewSignature(funcType *ast.FuncType, decls typeDecls) *Signature {
+	sig := &Signature{
+		Params:  newFields(funcType.Params),
+		Results: newFields(funcType.Results),
+	}
+
+	if funcType.TypeParams != nil {
+		names, args := typeArgs(funcType.TypeParams, decls)
+		for _, name := range names {
+			sig.TypeArgs = append(sig.TypeArgs, types.ExprString(args[name]))
+		}
+		sig.instantiate(args)
+	}
+
+	return sig
+}
+
+// typeArgs returns the names of type parameters and their type arguments
+// keyed by the names.
+func typeArgs(typeParams *ast.FieldList, decls typeDecls) ([]string, map[string]ast.Expr) {
+	args := make(map[string]ast.Expr)
+	var names []string
+	for _, param := range newFields(typeParams) {
+		args[param.Name] = typeArg(param.Type, decls)
+		names = append(names, param.Name)
+	}
+
+	// Type arguments may refer to the other type parameters
+	// (e.g., `[S ~[]E, E any]`).
+	for range names {
+		for name, arg := range args {
+			args[name] = substitute(arg, args)
+		}
+	}
+
+	return names, args
+}
+
+// typeDecls are type declarations of package keyed by type names.
+// They are used to resolve constraints declared in package.
+type typeDecls map[string]*ast.TypeSpec
+
+// newTypeDecls returns the type declarations of goFiles. Declarations of
+// the latter files take precedence.
+func newTypeDecls(goFiles ...*GoFile) typeDecls {
+	decls := make(typeDecls)
+	for _, goFile := range goFiles {
+		for _, typ := range goFile.Types {
+			decls[typ.Name] = typ.Spec
+		}
+	}
+	return decls
+}
+
+// without returns a copy of decls without name (to resolve
+// recursive constraints).
+func (decls typeDecls) without(name string) typeDecls {
+	copied := make(typeDecls, len(decls))
+	for n, spec := range decls {
+		if n != name {
+			copied[n] = spec
+		}
+	}
+	return copied
+}
+
+// instantiate replaces type parameters in params and results
+// with type arguments keyed by type parameter names.
+func (s *Signature) instantiate(args map[string]ast.Expr) {
+	for _, field := range append(s.Params, s.Results...) {
+		field.Type = substitute(field.Type, args)
+	}
+}
+
+// qualify qualifies identifiers in params, results and type arguments
+// with names (see qualifiedNames).
+func (s *Signature) qualify(names map[string]ast.Expr) {
+	s.instantiate(names)
+	for i, arg := range s.TypeArgs {
+		s.TypeArgs[i] = substituteString(arg, names)
+	}
+}
+
+// newFields flattens field list so that each Field has one name
+// (e.g., `a, b int` becomes two fields).
+func newFields(list *ast.FieldList) []*Field {
+	if list == nil {
+		return nil
+	}
+
+	var fields []*Field
+	for _, field := range list.List {
+		if len(field.Names) == 0 {
+			fields = append(fields, &Field{Type: field.Type})
+			continue
+		}
+
+		for _, name := range field.Names {
+			fields = append(fields, &Field{
+				Name: name.Name,
+				Type: field.Type,
+			})
+		}
+	}
+
+	return fields
+}
+
+// ParamNames returns variable names to be used for parameters
+// in generated code. Unnamed or blank parameters get `argN` names.
+func (s *Signature) ParamNames() []string {
+	names := make([]string, 0, len(s.Params))
+	for i, param := range s.Params {
+		name := param.Name
+		if name == "" || name == "_" {
+			name = fmt.Sprintf("arg%d", i)
+		}
+		names = append(names, name)
+	}
+	return names
+}
+
+// IsVariadic returns true if the last param is variadic.
+func (s *Signature) IsVariadic() bool {
+	return len(s.Params) > 0 && s.Params[len(s.Params)-1].IsVariadic()
+}
+
+// knownConstraints are type arguments for constraints which
+// can't be used as types.
+var knownConstraints = map[string]string{
+	"cmp.Ordered":          "int",
+	"constraints.Ordered":  "int",
+	"constraints.Integer":  "int",
+	"constraints.Signed":   "int",
+	"constraints.Unsigned": "uint",
+	"constraints.Float":    "float64",
+	"constraints.Complex":  "complex128",
+}
+
+// typeArg returns the type argument for type parameter constrained
+// by constraint: `any` for `any` and `comparable`, the first term of
+// union (e.g., `int` for `~int | ~string`) and the constraint itself
+// for basic interface (e.g., `fmt.Stringer`). Constraints declared in
+// package (decls) are resolved to their declarations.
+func typeArg(constraint ast.Expr, decls typeDecls) ast.Expr {
+	switch x := constraint.(type) {
+	case *ast.Ident:
+		if x.Name == "any" || x.Name == "comparable" {
+			return ast.NewIdent("any")
+		}
+		if spec, ok := decls[x.Name]; ok && spec.TypeParams == nil {
+			// Basic interfaces are kept as they are (e.g., `Shape`).
+			ifaceType, ok := spec.Type.(*ast.InterfaceType)
+			if ok {
+				if arg := typeArg(ifaceType, decls.without(x.Name)); arg != ast.Expr(ifaceType) {
+					return arg
+				}
+			}
+		}
+	case *ast.SelectorExpr:
+		if arg, ok := knownConstraints[types.ExprString(x)]; ok {
+			return ast.NewIdent(arg)
+		}
+	case *ast.BinaryExpr:
+		// Union (e.g., `~int | ~string`)
+		return typeArg(x.X, decls)
+	case *ast.UnaryExpr:
+		// Underlying type (e.g., `~int`)
+		return x.X
+	case *ast.InterfaceType:
+		if x.Methods == nil || len(x.Methods.List) == 0 {
+			return ast.NewIdent("any")
+		}
+		// Type elements or an embedded constraint (e.g., `interface{ Number }`)
+		if hasTypeElems(x) || (len(x.Methods.List) == 1 && len(x.Methods.List[0].Names) == 0) {
+			return typeArg(x.Methods.List[0].Type, decls)
+		}
+	}
+	return constraint
+}
+
+// substitute returns a copy of type expr whose identifiers are replaced
+// with the expressions in args keyed by the identifier names.
+func substitute(expr ast.Expr, args map[string]ast.Expr) ast.Expr {
+	if len(args) == 0 {
+		return expr
+	}
+
+	// Ellipsis (of variadic param) is not an expression.
+	if ellipsis, ok := expr.(*ast.Ellipsis); ok {
+		return &ast.Ellipsis{Elt: substitute(ellipsis.Elt, args)}
+	}
+
+	// Copy expr not to modify AST of source.
+	copied, err := parser.ParseExpr(types.ExprString(expr))
+	if err != nil {
+		return expr
+	}
+
+	return astutil.Apply(copied, func(c *astutil.Cursor) bool {
+		ident, ok := c.Node().(*ast.Ident)
+		if !ok {
+			return true
+		}
+
+		// Ignore package qualified names (e.g., `pkg.T`) and names of
+		// fields and params in type literals (e.g., `struct{ T int }`).
+		if _, ok := c.Parent().(*ast.SelectorExpr); ok && c.Name() == "Sel" {
+			return true
+		}
+		if _, ok := c.Parent().(*ast.Field); ok && c.Name() == "Names" {
+			return true
+		}
+
+		if arg, ok := args[ident.Name]; ok {
+			c.Replace(arg)
+		}
+		return true
+	}, nil).(ast.Expr)
+}
+
+// substituteString is substitute for type expression written in string.
+// It returns s as it is if it's not an expression.
+func substituteString(s string, args map[string]ast.Expr) string {
+	expr, err := parser.ParseExpr(s)
+	if err != nil {
+		return s
+	}
+	return types.ExprString(substitute(expr, args))
+}
+
+// funcStub returns a function literal of funcType which does nothing
+// and returns zero values. Results are named so that it can return
+// zero values of any type.
+func funcStub(funcType *ast.FuncType) string {
+	var params []string
+	for _, param := range newFields(funcType.Params) {
+		params = append(params, param.TypeString())
+	}
+
+	var results []string
+	for i, result := range newFields(funcType.Results) {
+		results = append(results, fmt.Sprintf("r%d %s", i, result.TypeString()))
+	}
+
+	if len(results) == 0 {
+		return fmt.Sprintf("func(%s) {\n// TODO: Stub the function.\n}", joinArgs(params))
+	}
+	return fmt.Sprintf("func(%s) (%s) {\n// TODO: Stub the function.\nreturn\n}",
+		joinArgs(params), strings.Join(results, ", "))
+}
@@ -0,0 +1,165 @@
+package main
+
+import (
+	"go/ast"
+	"go/parser"
+	"go/types"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestNewSignature(t *testing.T) {
+	src := `package p
+
+func F(a, b int, _ string, c []byte) (n int, err error) { return }
+`
+	goFile, err := parse("p.go", strings.NewReader(src))
+	if err != nil {
+		t.Fatalf("parse failed: %s", err)
+	}
+
+	sig := goFile.Funcs[0].Signature
+
+	var params []string
+	for _, param := range sig.Params {
+		params = append(params, param.Name+" "+param.TypeString())
+	}
+
+	expected := []string{"a int", "b int", "_ string", "c []byte"}
+	if !reflect.DeepEqual(params, expected) {
+		t.Errorf("expected %q to eq %q", params, expected)
+	}
+
+	expected = []string{"a", "b", "arg2", "c"}
+	if names := sig.ParamNames(); !reflect.DeepEqual(names, expected) {
+		t.Errorf("expected %q to eq %q", names, expected)
+	}
+
+	if len(sig.Results) != 2 {
+		t.Errorf("expected %d to eq %d", len(sig.Results), 2)
+	}
+}
+
+func TestNewSignature_generic(t *testing.T) {
+	src := `package p
+
+func Keys[M ~map[K]V, K comparable, V any](m M, opts ...K) []K { return nil }
+`
+	goFile, err := parse("p.go", strings.NewReader(src))
+	if err != nil {
+		t.Fatalf("parse failed: %s", err)
+	}
+
+	sig := goFile.Funcs[0].Signature
+
+	expected := []string{"map[any]any", "any", "any"}
+	if !reflect.DeepEqual(sig.TypeArgs, expected) {
+		t.Errorf("expected %q to eq %q", sig.TypeArgs, expected)
+	}
+
+	var varTypes []string
+	for _, field := range append(sig.Params, sig.Results...) {
+		varTypes = append(varTypes, field.VarType())
+	}
+
+	expected = []string{"map[any]any", "[]any", "[]any"}
+	if !reflect.DeepEqual(varTypes, expected) {
+		t.Errorf("expected %q to eq %q", varTypes, expected)
+	}
+
+	// AST of source must be kept.
+	if typ := types.ExprString(goFile.Funcs[0].Decl.Type.Params.List[0].Type); typ != "M" {
+		t.Errorf("expected %q to eq %q", typ, "M")
+	}
+}
+
+func TestNewSignature_localConstraint(t *testing.T) {
+	src := `package p
+
+type Number interface {
+	~int64 | ~float64
+}
+
+type Ordered interface{ Number }
+
+type Shape interface{ Area() float64 }
+
+func Max[T Number](a, b T) T { return a }
+
+func Largest[T Shape](shapes []T) T { return shapes[0] }
+
+type Stack[T Ordered, S ~[]T] struct{ items S }
+
+func (s *Stack[E, _]) Push(v E) {}
+`
+	goFile, err := parse("p.go", strings.NewReader(src))
+	if err != nil {
+		t.Fatalf("parse failed: %s", err)
+	}
+
+	cases := []struct {
+		typeArgs []string
+		expected []string
+	}{
+		{goFile.Funcs[0].Signature.TypeArgs, []string{"int64"}},
+		{goFile.Funcs[1].Signature.TypeArgs, []string{"Shape"}},
+		{goFile.Methods[0].RecvTypeArgs, []string{"int64", "[]int64"}},
+	}
+	for _, tc := range cases {
+		if !reflect.DeepEqual(tc.typeArgs, tc.expected) {
+			t.Errorf("expected %q to eq %q", tc.typeArgs, tc.expected)
+		}
+	}
+
+	if typ := goFile.Methods[0].Signature.Params[0].TypeString(); typ != "int64" {
+		t.Errorf("expected %q to eq %q", typ, "int64")
+	}
+}
+
+func TestTypeArg(t *testing.T) {
+	cases := []struct {
+		constraint string
+		expected   string
+	}{
+		{"any", "any"},
+		{"comparable", "any"},
+		{"interface{}", "any"},
+		{"~int | ~string", "int"},
+		{"interface{ ~float64 }", "float64"},
+		{"cmp.Ordered", "int"},
+		{"fmt.Stringer", "fmt.Stringer"},
+	}
+
+	for _, tc := range cases {
+		expr, err := parser.ParseExpr(tc.constraint)
+		if err != nil {
+			t.Fatalf("parse failed: %s", err)
+		}
+
+		if got := types.ExprString(typeArg(expr, nil)); got != tc.expected {
+			t.Errorf("%s: expected %q to eq %q", tc.constraint, got, tc.expected)
+		}
+	}
+}
+
+func TestFuncStub(t *testing.T) {
+	cases := []struct {
+		typ      string
+		expected string
+	}{
+		{"func()", "func() {\n// TODO: Stub the function.\n}"},
+		{"func(a, b int) (n int, err error)", "func(int, int) (r0 int, r1 error) {\n// TODO: Stub the function.\nreturn\n}"},
+	}
+
+	for _, tc := range cases {
+		expr, err := parser.ParseExpr(tc.typ)
+		if err != nil {
+			t.Fatalf("parse failed: %s", err)
+		}
+
+		if got := funcStub(expr.(*ast.FuncType)); got != tc.expected {
+			t.Errorf("expected %q to eq %q", got, tc.expected)
+		}
+	}
+}
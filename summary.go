@@ -0,0 +1,199 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"path/filepath"
+	"text/tabwriter"
+)
+
+// summaryCounts are the counts of functions and methods of source files
+// and their tests.
+type summaryCounts struct {
+	ExportedFuncs     int `json:"exported_funcs"`
+	UnexportedFuncs   int `json:"unexported_funcs"`
+	ExportedMethods   int `json:"exported_methods"`
+	UnexportedMethods int `json:"unexported_methods"`
+
+	// Targets are the functions and methods whose tests are generated
+	// (unexported ones are included only with -i).
+	Targets int `json:"targets"`
+
+	// Tested are Targets which have tests (per Mode) and Missing are
+	// the others, whose tests would be generated.
+	Tested  int `json:"tested"`
+	Missing int `json:"missing"`
+
+	// Coverage is the percentage of Tested in Targets
+	// ("test presence coverage").
+	Coverage float64 `json:"coverage"`
+}
+
+func (c *summaryCounts) add(other *summaryCounts) {
+	c.ExportedFuncs += other.ExportedFuncs
+	c.UnexportedFuncs += other.UnexportedFuncs
+	c.ExportedMethods += other.ExportedMethods
+	c.UnexportedMethods += other.UnexportedMethods
+	c.Targets += other.Targets
+	c.Tested += other.Tested
+	c.Missing += other.Missing
+
+	c.Coverage = 100
+	if c.Targets > 0 {
+		c.Coverage = float64(c.Tested) * 100 / float64(c.Targets)
+	}
+}
+
+// packageSummary is the summary of source files in a directory.
+type packageSummary struct {
+	Dir  string `json:"dir"`
+	Name string `json:"name"`
+	summaryCounts
+}
+
+// summaryReport is the summary of all source files (see -summary).
+type summaryReport struct {
+	Mode     string            `json:"mode"`
+	Packages []*packageSummary `json:"packages"`
+	Total    summaryCounts     `json:"total"`
+}
+
+func newSummaryReport(mode Mode) *summaryReport {
+	return &summaryReport{
+		Mode:     mode.String(),
+		Packages: []*packageSummary{},
+		Total:    summaryCounts{Coverage: 100},
+	}
+}
+
+// add adds the counts of a source file in dir which declares
+// the package of name.
+func (r *summaryReport) add(dir, name string, counts *summaryCounts) {
+	var pkg *packageSummary
+	for _, p := range r.Packages {
+		if p.Dir == dir && p.Name == name {
+			pkg = p
+		}
+	}
+	if pkg == nil {
+		pkg = &packageSummary{Dir: dir, Name: name}
+		r.Packages = append(r.Packages, pkg)
+	}
+
+	pkg.add(counts)
+	r.Total.add(counts)
+}
+
+// writeTable writes the report as a table.
+func (r *summaryReport) writeTable(w io.Writer) error {
+	tw := tabwriter.NewWriter(w, 0, 8, 2, ' ', 0)
+	fmt.Fprintln(tw, "PACKAGE\tFUNCS\tMETHODS\tTARGETS\tTESTED\tMISSING\tCOVERAGE")
+
+	row := func(name string, c *summaryCounts) {
+		fmt.Fprintf(tw, "%s\t%d/%d\t%d/%d\t%d\t%d\t%d\t%.1f%%\n",
+			name,
+			c.ExportedFuncs, c.UnexportedFuncs,
+			c.ExportedMethods, c.UnexportedMethods,
+			c.Targets, c.Tested, c.Missing, c.Coverage)
+	}
+
+	for _, pkg := range r.Packages {
+		row(pkg.Dir+" ("+pkg.Name+")", &pkg.summaryCounts)
+	}
+	row("TOTAL", &r.Total)
+
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+
+	_, err := fmt.Fprintf(w, "\nFUNCS and METHODS are exported/unexported. Tests are found by %s mode.\n", r.Mode)
+	return err
+}
+
+// writeJSON writes the report as JSON.
+func (r *summaryReport) writeJSON(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(r)
+}
+
+// summarize returns the counts of the functions and methods of the source
+// file and their tests in the test file, and the package name.
+func summarize(srcPath, testPath string, opts *generateOpts) (*summaryCounts, string, error) {
+	goFile, err := parseFileOverlay(srcPath, opts.overlay)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to parse go file: %w", err)
+	}
+
+	goTestFile, err := openTestFile(testPath, goFile.PackageName, opts.overlay)
+	if err != nil {
+		return nil, "", err
+	}
+
+	diffOpts := opts.diffOpts
+	diffOpts.init()
+
+	counts := &summaryCounts{}
+	for _, fun := range goFile.Funcs {
+		if contains(diffOpts.IgnoreFuncs, fun.Name) {
+			continue
+		}
+
+		if isUnExported(fun.Name) {
+			counts.UnexportedFuncs++
+			if !diffOpts.IncludeUnexported {
+				continue
+			}
+		} else {
+			counts.ExportedFuncs++
+		}
+		counts.Targets++
+	}
+
+	for _, method := range goFile.Methods {
+		if isUnExported(method.Name) {
+			counts.UnexportedMethods++
+			if !diffOpts.IncludeUnexported {
+				continue
+			}
+		} else {
+			counts.ExportedMethods++
+		}
+		counts.Targets++
+	}
+
+	// Missing tests are the ones gotests generates.
+	diffFuncs, err := goFile.diffFuncs(goTestFile, diffOpts)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to diff source file and test file: %s", err)
+	}
+
+	diffMethods, err := goFile.diffMethods(goTestFile, diffOpts)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to diff source file and test file: %s", err)
+	}
+
+	counts.Missing = len(diffFuncs) + len(diffMethods)
+	counts.Tested = counts.Targets - counts.Missing
+
+	return counts, goFile.PackageName, nil
+}
+
+// processSummary adds the counts of the source file to opts.summary.
+func (cli *CLI) processSummary(srcPath, testPath string, opts *generateOpts) int {
+	counts, name, err := summarize(srcPath, testPath, opts)
+	if err != nil {
+		cli.reportError(srcPath, err)
+		return ExitCodeError
+	}
+
+	dir, err := fmtPath(filepath.Dir(srcPath))
+	if err != nil {
+		cli.reportError(srcPath, err)
+		return ExitCodeError
+	}
+
+	opts.summary.add(dir, name, counts)
+	return ExitCodeOK
+}
@@ -0,0 +1,100 @@
+package main
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestSummarize(t *testing.T) {
+	dir, err := ioutil.TempDir("", Name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	src := `package p
+
+type User struct{}
+
+func (u *User) Insert() {}
+
+func (u *User) validate() {}
+
+func Add() {}
+
+func sub() {}
+
+func init() {}
+`
+	testSrc := `package p
+
+import "testing"
+
+func TestAdd(t *testing.T) {}
+
+func TestSub(t *testing.T) {}
+`
+	srcPath := filepath.Join(dir, "p.go")
+	testPath := filepath.Join(dir, "p_test.go")
+	if err := ioutil.WriteFile(srcPath, []byte(src), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(testPath, []byte(testSrc), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	cases := []struct {
+		includeUnexported bool
+		expected          summaryCounts
+	}{
+		{false, summaryCounts{
+			ExportedFuncs: 1, UnexportedFuncs: 1, ExportedMethods: 1, UnexportedMethods: 1,
+			Targets: 2, Tested: 1, Missing: 1,
+		}},
+		{true, summaryCounts{
+			ExportedFuncs: 1, UnexportedFuncs: 1, ExportedMethods: 1, UnexportedMethods: 1,
+			Targets: 4, Tested: 2, Missing: 2,
+		}},
+	}
+
+	for _, c := range cases {
+		opts := &generateOpts{diffOpts: &diffOpts{IncludeUnexported: c.includeUnexported}}
+		counts, name, err := summarize(srcPath, testPath, opts)
+		if err != nil {
+			t.Fatalf("summarize failed: %s", err)
+		}
+
+		if name != "p" {
+			t.Errorf("expected %q to eq %q", name, "p")
+		}
+		if *counts != c.expected {
+			t.Errorf("expected %+v to eq %+v", *counts, c.expected)
+		}
+	}
+}
+
+func TestSummaryReport_writeTable(t *testing.T) {
+	r := newSummaryReport(Strict)
+	r.add("a", "a", &summaryCounts{ExportedFuncs: 2, Targets: 2, Tested: 1, Missing: 1})
+	r.add("b", "b", &summaryCounts{ExportedMethods: 1, UnexportedMethods: 1, Targets: 1, Missing: 1})
+	r.add("a", "a", &summaryCounts{ExportedFuncs: 1, Targets: 1, Tested: 1})
+
+	var buf bytes.Buffer
+	if err := r.writeTable(&buf); err != nil {
+		t.Fatalf("writeTable failed: %s", err)
+	}
+
+	expected := `PACKAGE  FUNCS  METHODS  TARGETS  TESTED  MISSING  COVERAGE
+a (a)    3/0    0/0      3        2       1        66.7%
+b (b)    0/0    1/1      1        0       1        0.0%
+TOTAL    3/0    1/1      4        2       2        50.0%
+
+FUNCS and METHODS are exported/unexported. Tests are found by strict mode.
+`
+	if got := buf.String(); got != expected {
+		t.Errorf("expected %q to eq %q", got, expected)
+	}
+}
@@ -0,0 +1,451 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"go/ast"
+	"strings"
+	"text/template"
+)
+
+// tableTestBodyTmpl is the body of generated table-driven test.
+// Comparisons of results are defined by each AssertStyle
+// (see assertTmpls).
+var tableTestBodyTmpl = template.Must(template.New("table").Parse(`
+{{- if .Parallel }}
+	t.Parallel()
+{{- end }}
+{{- with .Args }}
+	type args struct {
+	{{- range . }}
+		{{ .Name }} {{ .Type }}
+	{{- end }}
+	}
+{{- end }}
+	tests := []struct {
+		name string
+		{{- with .Recv }}
+		{{ .Name }} {{ .Type }}
+		{{- end }}
+		{{- if .Args }}
+		args args
+		{{- end }}
+		{{- if and .Context .Context.Cancel }}
+		canceled bool
+		{{- end }}
+		{{- range .Results }}
+		{{- if not (or .Golden .IsFunc) }}
+		{{ .Want }} {{ if .IsError }}bool{{ else }}{{ .Type }}{{ end }}
+		{{- end }}
+		{{- end }}
+	}{
+		// TODO: Add test cases.
+		{{- range .Cases }}
+		{{- $c := "" }}
+		{{- with .TODO }}
+		// TODO: {{ . }}
+		{{- $c = "//\t" }}
+		{{- end }}
+		{{ if $c }}// {{ end }}{
+			{{ $c }}name: {{ printf "%q" .Name }},
+			{{- with .Args }}
+			{{ $c }}args: args{
+				{{- range . }}
+				{{ $c }}{{ .Name }}: {{ .Value }},
+				{{- end }}
+			{{ $c }}},
+			{{- end }}
+			{{- range .Fields }}
+			{{ $c }}{{ .Name }}: {{ .Value }},
+			{{- end }}
+		{{ if $c }}// {{ end }}},
+		{{- end }}
+	}
+	for _, tt := range tests {
+		{{- if .CaptureLoopVar }}
+		tt := tt
+		{{- end }}
+		t.Run(tt.name, func(t *testing.T) {
+			{{- if .Parallel }}
+			t.Parallel()
+			{{- end }}
+			{{- if .Asserts }}
+			{{- template "setup" . }}
+			{{- end }}
+			{{- with .Context }}
+			ctx := {{ .Expr }}
+			{{- if .Cancel }}
+			if tt.canceled {
+				var cancel context.CancelFunc
+				ctx, cancel = context.WithCancel(ctx)
+				cancel()
+			}
+			{{- end }}
+			{{- end }}
+			{{- range .Locals }}
+			{{ .Decl }}
+			{{- end }}
+			{{- range .LocalStmts }}
+			{{ . }}
+			{{- end }}
+			{{ with .Results }}{{ $.Gots }} := {{ end }}{{ .Call }}
+			{{- range .Checks }}
+			{{- if .IsError }}
+			{{- template "errCheck" ($.Check .) }}
+			{{- if gt (len $.Checks) 1 }}
+			if tt.wantErr {
+				return
+			}
+			{{- end }}
+			{{- else if .Golden }}
+			{{- template "golden" ($.Check .) }}
+			{{- else if .IsFunc }}
+			// TODO: Check the behavior of the returned function.
+			if {{ .Got }} == nil {
+				t.Errorf("{{ $.Name }}() {{ .Got }} = nil")
+			}
+			{{- else }}
+			{{- template "compare" ($.Check .) }}
+			{{- end }}
+			{{- end }}
+		})
+	}
+`))
+
+// impliesTable enables table-driven test body if any option which works
+// only in it is given: fakes of -mocks are seeded in test cases, and
+// -golden and -assert change the comparisons of results.
+func (opts *generateOpts) impliesTable() {
+	opts.table = opts.table || opts.mocks || opts.golden || opts.assert != StdAssert
+}
+
+// tableTestFields are field names of test case struct which
+// must not be used for receiver.
+var tableTestFields = []string{"name", "args", "canceled", "want", "wantErr"}
+
+// tableTestLocals are variable names (and packages) used in subtest
+// which must not be used for local variables of params and results.
+var tableTestLocals = []string{
+	"t", "tt", "tests", "c", "ctx", "cancel", "err", "golden", "want",
+	"assert", "bytes", "cmp", "context", "filepath", "io", "os", "qt",
+	"reflect", "require",
+}
+
+// tableTestData is the data for tableTestBodyTmpl.
+type tableTestData struct {
+	// Name is the target name used in failure messages.
+	Name string
+
+	Recv    *Var
+	Args    []*Var
+	Results []*Result
+
+	// Checks are Results in the order of checking them. Error is
+	// checked first and the others are not checked if error is expected.
+	Checks []*Result
+
+	// Gots is the left hand side of the call (e.g., `got, err`).
+	Gots string
+	Call string
+
+	Cases []*tableTestCase
+
+	// Context is set if target takes context.Context as the first param.
+	Context *tableTestContext
+
+	// Locals are variables of params created in each subtest (e.g.,
+	// channels) instead of fields of test case. LocalStmts are run
+	// after declaring them.
+	Locals     []*Var
+	LocalStmts []string
+
+	Parallel       bool
+	CaptureLoopVar bool
+}
+
+// Asserts returns true if any result is checked by the templates of
+// AssertStyle ("errCheck" and "compare"), which need "setup". Golden
+// files and functions are checked in the same way for all styles.
+func (d *tableTestData) Asserts() bool {
+	for _, result := range d.Results {
+		if result.IsError || !(result.Golden || result.IsFunc) {
+			return true
+		}
+	}
+	return false
+}
+
+// resultCheck is the data for templates checking a result.
+type resultCheck struct {
+	*Result
+
+	// Name is the target name used in failure messages.
+	Name string
+}
+
+// Check returns the data for checking the result.
+func (d *tableTestData) Check(r *Result) *resultCheck {
+	return &resultCheck{Result: r, Name: d.Name}
+}
+
+// Bytes returns the expression of the result as []byte
+// for comparing with golden file.
+func (c *resultCheck) Bytes() string {
+	switch c.Type {
+	case "string":
+		return "[]byte(" + c.Got + ")"
+	case "io.Reader":
+		return c.Got + "Bytes"
+	default:
+		return c.Got
+	}
+}
+
+// tableTestContext is the context given to target in each subtest.
+type tableTestContext struct {
+	// Expr is the expression creating the context
+	// (e.g., `context.Background()`).
+	Expr string
+
+	// Cancel adds `canceled` field to test case. If it's true,
+	// the context is canceled before calling target.
+	Cancel bool
+}
+
+// tableTestCase is a test case seeded in generated table.
+type tableTestCase struct {
+	Name string
+	Args []*FieldValue
+
+	// Fields are the other fields of the test case (e.g., `wantErr`).
+	Fields []*FieldValue
+
+	// TODO comments out the test case with it if it's not empty. It's
+	// for the case which may not pass (e.g., target ignores context).
+	TODO string
+}
+
+// wantErr returns true if the test case expects error.
+func (c *tableTestCase) wantErr() bool {
+	for _, field := range c.Fields {
+		if field.Name == "wantErr" && field.Value == "true" {
+			return true
+		}
+	}
+	return false
+}
+
+// FieldValue is a field and its value expression in composite literal.
+type FieldValue struct {
+	Name  string
+	Value string
+}
+
+// testBuilder builds test functions.
+type testBuilder struct {
+	// table enables table-driven test body. Otherwise it builds
+	// an empty test function.
+	table bool
+
+	// style is the style of comparisons in table-driven test.
+	style AssertStyle
+
+	// fakes are fake type names of interfaces (see addFakes) keyed by
+	// the interface types. If target has interface params which have
+	// fakes, a test case using them is seeded.
+	fakes map[string]string
+
+	// parallel makes test and its subtests call t.Parallel.
+	parallel bool
+
+	// captureLoopVar re-declares the loop variable in the loop body
+	// (`tt := tt`). It's needed before Go 1.22 where subtests running
+	// in parallel would share the same loop variable.
+	captureLoopVar bool
+
+	// testContext uses t.Context() (Go 1.24 or later) for
+	// context.Context param instead of context.Background().
+	testContext bool
+
+	// doc adds the first sentence of doc comment of target above
+	// the test and seeds test cases from it (see parseDocComment).
+	doc bool
+
+	// golden compares results of goldenTypes with golden files.
+	golden bool
+
+	// goldenPaths are paths of golden files (see goldenPath) of
+	// the built tests.
+	goldenPaths []string
+}
+
+// build builds test function. It's declBuilder.
+func (b *testBuilder) build(name string, t *target) ([]byte, error) {
+	var dc *docComment
+	if b.doc {
+		dc = parseDocComment(t.Doc)
+	}
+
+	text, err := b.buildTest(name, t, dc)
+	if err != nil || dc == nil || dc.Synopsis == "" {
+		return text, err
+	}
+
+	return append([]byte("// "+dc.Synopsis+"\n"), text...), nil
+}
+
+// buildTest builds test function. Test cases are seeded from dc
+// if it's not nil.
+func (b *testBuilder) buildTest(name string, t *target, dc *docComment) ([]byte, error) {
+	if !b.table {
+		body := ""
+		if b.parallel {
+			body = "t.Parallel()"
+		}
+		return formatFuncDecl(NewTestFuncDecl(name), body)
+	}
+
+	if t.IsMethod() && contains(tableTestFields, t.RecvVar) {
+		t.RecvVar = "recv"
+	}
+
+	data := &tableTestData{
+		Name:           t.FullName(),
+		Results:        t.Results(tableTestLocals...),
+		Parallel:       b.parallel,
+		CaptureLoopVar: b.parallel && b.captureLoopVar,
+	}
+
+	if t.IsMethod() {
+		data.Recv = &Var{Name: t.RecvVar, Type: t.RecvType}
+	}
+
+	var args []string
+	var fakeArgs, stubArgs []*FieldValue
+	for i, param := range t.Signature.Params {
+		// The first context.Context param is not a field of test case
+		// but the context created in each subtest.
+		if i == 0 && param.TypeString() == "context.Context" {
+			data.Context = &tableTestContext{Expr: "context.Background()"}
+			if b.testContext {
+				data.Context.Expr = "t.Context()"
+			}
+			args = append(args, "ctx")
+			continue
+		}
+
+		// Channel is created (and closed) in each subtest.
+		if chanType, ok := param.Type.(*ast.ChanType); ok {
+			local := t.ParamVars[i]
+			if contains(tableTestLocals, local) {
+				local = fmt.Sprintf("arg%d", i)
+			}
+			data.Locals = append(data.Locals, paramVar(local, param))
+			data.LocalStmts = append(data.LocalStmts, chanStmts(local, chanType)...)
+			args = append(args, local)
+			continue
+		}
+
+		data.Args = append(data.Args, &Var{
+			Name: t.ParamVars[i],
+			Type: param.VarType(),
+		})
+		args = append(args, "tt.args."+t.ParamVars[i])
+
+		if fake, ok := b.fakes[param.TypeString()]; ok {
+			fakeArgs = append(fakeArgs, &FieldValue{
+				Name:  t.ParamVars[i],
+				Value: "&" + fake + "{}",
+			})
+		}
+
+		if funcType, ok := param.Type.(*ast.FuncType); ok {
+			stubArgs = append(stubArgs, &FieldValue{
+				Name:  t.ParamVars[i],
+				Value: funcStub(funcType),
+			})
+		}
+	}
+
+	// Fakes and function stubs are seeded in the same test case.
+	switch {
+	case len(fakeArgs) > 0:
+		data.Cases = append(data.Cases, &tableTestCase{
+			Name: "with fakes",
+			Args: append(fakeArgs, stubArgs...),
+		})
+	case len(stubArgs) > 0:
+		data.Cases = append(data.Cases, &tableTestCase{
+			Name: "with stubs",
+			Args: stubArgs,
+		})
+	}
+
+	if dc != nil {
+		for _, c := range dc.Cases {
+			data.Cases = append(data.Cases, &tableTestCase{Name: c})
+		}
+	}
+
+	if data.Context != nil && t.HasResults() {
+		results := data.Results
+		if results[len(results)-1].IsError {
+			data.Context.Cancel = true
+			data.Cases = append(data.Cases, &tableTestCase{
+				Name: "canceled context",
+				Fields: []*FieldValue{
+					{Name: "canceled", Value: "true"},
+					{Name: "wantErr", Value: "true"},
+				},
+				TODO: "Uncomment if " + data.Name + " returns error on canceled context.",
+			})
+		}
+	}
+
+	if b.golden && markGolden(data.Results) {
+		// Golden file needs a test case (not expecting error since the
+		// file is not read) to be named after.
+		var paths []string
+		for _, c := range data.Cases {
+			if !c.wantErr() {
+				paths = append(paths, goldenPath(name, c.Name))
+			}
+		}
+		if len(paths) == 0 {
+			data.Cases = append([]*tableTestCase{{Name: "default"}}, data.Cases...)
+			paths = append(paths, goldenPath(name, "default"))
+		}
+		b.goldenPaths = append(b.goldenPaths, paths...)
+	}
+
+	for _, result := range data.Results {
+		if result.IsError {
+			data.Checks = append([]*Result{result}, data.Checks...)
+		} else {
+			data.Checks = append(data.Checks, result)
+		}
+	}
+
+	var gots []string
+	for _, result := range data.Results {
+		gots = append(gots, result.Got)
+	}
+	data.Gots = strings.Join(gots, ", ")
+	data.Call = t.callExpr("tt."+t.RecvVar, args)
+
+	var buf bytes.Buffer
+	if err := tableTestTmpls[b.style].Execute(&buf, data); err != nil {
+		return nil, err
+	}
+
+	return formatFuncDecl(NewTestFuncDecl(name), buf.String())
+}
+
+// typeName returns the name of the type declared in the same package.
+// It returns empty string if expr is not such a type.
+func typeName(expr ast.Expr) string {
+	if ident, ok := expr.(*ast.Ident); ok {
+		return ident.Name
+	}
+	return ""
+}
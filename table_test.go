@@ -0,0 +1,365 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestTestBuilder_table(t *testing.T) {
+	src := `package p
+
+type Store interface{}
+
+func Sync(s Store) (int, error) { return 0, nil }
+`
+	goFile, err := parse("p.go", strings.NewReader(src))
+	if err != nil {
+		t.Fatalf("parse failed: %s", err)
+	}
+
+	builder := &testBuilder{
+		table: true,
+		fakes: map[string]string{"Store": "fakeStore"},
+	}
+	res, err := builder.build("TestSync", funcTarget(goFile.Funcs[0]))
+	if err != nil {
+		t.Fatalf("build failed: %s", err)
+	}
+
+	expected := `func TestSync(t *testing.T) {
+	type args struct {
+		s Store
+	}
+	tests := []struct {
+		name    string
+		args    args
+		want    int
+		wantErr bool
+	}{
+		// TODO: Add test cases.
+		{
+			name: "with fakes",
+			args: args{
+				s: &fakeStore{},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Sync(tt.args.s)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Sync() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Sync() got = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}`
+	if string(res) != expected {
+		t.Errorf("expected %q to eq %q", res, expected)
+	}
+}
+
+func TestTestBuilder_parallel(t *testing.T) {
+	src := `package p
+
+func Reset() {}
+`
+	goFile, err := parse("p.go", strings.NewReader(src))
+	if err != nil {
+		t.Fatalf("parse failed: %s", err)
+	}
+
+	cases := []struct {
+		builder  *testBuilder
+		expected string
+	}{
+		{
+			builder: &testBuilder{parallel: true},
+			expected: `func TestReset(t *testing.T) {
+	t.Parallel()
+}`,
+		},
+
+		{
+			builder: &testBuilder{table: true, parallel: true, captureLoopVar: true},
+			expected: `func TestReset(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name string
+	}{
+		// TODO: Add test cases.
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			Reset()
+		})
+	}
+}`,
+		},
+	}
+
+	for _, tc := range cases {
+		res, err := tc.builder.build("TestReset", funcTarget(goFile.Funcs[0]))
+		if err != nil {
+			t.Fatalf("build failed: %s", err)
+		}
+
+		if string(res) != tc.expected {
+			t.Errorf("expected %q to eq %q", res, tc.expected)
+		}
+	}
+}
+
+func TestTestBuilder_context(t *testing.T) {
+	src := `package p
+
+import "context"
+
+func Ping(ctx context.Context, host string) error { return nil }
+`
+	goFile, err := parse("p.go", strings.NewReader(src))
+	if err != nil {
+		t.Fatalf("parse failed: %s", err)
+	}
+
+	cases := []struct {
+		builder *testBuilder
+		ctx     string
+	}{
+		{&testBuilder{table: true}, "context.Background()"},
+		{&testBuilder{table: true, testContext: true}, "t.Context()"},
+	}
+
+	for _, tc := range cases {
+		res, err := tc.builder.build("TestPing", funcTarget(goFile.Funcs[0]))
+		if err != nil {
+			t.Fatalf("build failed: %s", err)
+		}
+
+		expected := `func TestPing(t *testing.T) {
+	type args struct {
+		host string
+	}
+	tests := []struct {
+		name     string
+		args     args
+		canceled bool
+		wantErr  bool
+	}{
+		// TODO: Add test cases.
+		// TODO: Uncomment if Ping returns error on canceled context.
+		// {
+		//	name: "canceled context",
+		//	canceled: true,
+		//	wantErr: true,
+		// },
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := ` + tc.ctx + `
+			if tt.canceled {
+				var cancel context.CancelFunc
+				ctx, cancel = context.WithCancel(ctx)
+				cancel()
+			}
+			err := Ping(ctx, tt.args.host)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Ping() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}`
+		if string(res) != expected {
+			t.Errorf("expected %q to eq %q", res, expected)
+		}
+	}
+}
+
+func TestTestBuilder_results(t *testing.T) {
+	src := `package p
+
+type Config struct{}
+
+func Parse(s string) (cfg *Config, n int, err error) { return }
+
+func Split(s string) (t, rest string) { return }
+`
+	goFile, err := parse("p.go", strings.NewReader(src))
+	if err != nil {
+		t.Fatalf("parse failed: %s", err)
+	}
+
+	builder := &testBuilder{table: true}
+	res, err := builder.build("TestParse", funcTarget(goFile.Funcs[0]))
+	if err != nil {
+		t.Fatalf("build failed: %s", err)
+	}
+
+	expected := `func TestParse(t *testing.T) {
+	type args struct {
+		s string
+	}
+	tests := []struct {
+		name    string
+		args    args
+		wantCfg *Config
+		wantN   int
+		wantErr bool
+	}{
+		// TODO: Add test cases.
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg, n, err := Parse(tt.args.s)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Parse() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if !reflect.DeepEqual(cfg, tt.wantCfg) {
+				t.Errorf("Parse() cfg = %v, want %v", cfg, tt.wantCfg)
+			}
+			if !reflect.DeepEqual(n, tt.wantN) {
+				t.Errorf("Parse() n = %v, want %v", n, tt.wantN)
+			}
+		})
+	}
+}`
+	if string(res) != expected {
+		t.Errorf("expected %q to eq %q", res, expected)
+	}
+
+	// Named result which conflicts with *testing.T is not used.
+	res, err = builder.build("TestSplit", funcTarget(goFile.Funcs[1]))
+	if err != nil {
+		t.Fatalf("build failed: %s", err)
+	}
+
+	expected = `			got, rest := Split(tt.args.s)`
+	if !strings.Contains(string(res), expected) {
+		t.Errorf("expected %q to contain %q", res, expected)
+	}
+}
+
+func TestTestBuilder_doc(t *testing.T) {
+	src := `package p
+
+// Reset resets the state.
+//
+//   - twice
+func Reset() {}
+`
+	goFile, err := parse("p.go", strings.NewReader(src))
+	if err != nil {
+		t.Fatalf("parse failed: %s", err)
+	}
+
+	builder := &testBuilder{doc: true}
+	res, err := builder.build("TestReset", funcTarget(goFile.Funcs[0]))
+	if err != nil {
+		t.Fatalf("build failed: %s", err)
+	}
+
+	expected := "// Reset resets the state.\nfunc TestReset(t *testing.T) {\n}"
+	if string(res) != expected {
+		t.Errorf("expected %q to eq %q", res, expected)
+	}
+
+	builder.table = true
+	res, err = builder.build("TestReset", funcTarget(goFile.Funcs[0]))
+	if err != nil {
+		t.Fatalf("build failed: %s", err)
+	}
+
+	expected = `		{
+			name: "twice",
+		},
+`
+	if !strings.Contains(string(res), expected) {
+		t.Errorf("expected %q to contain %q", res, expected)
+	}
+}
+
+func TestTestBuilder_funcResults(t *testing.T) {
+	goCmd, err := exec.LookPath("go")
+	if err != nil {
+		t.Skip("go command is not found")
+	}
+
+	dir, err := ioutil.TempDir("", Name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	src := `package p
+
+func F() func() { return func() {} }
+
+func G(n int) (func(int) int, error) { return nil, nil }
+
+func H() (int, func()) { return 0, nil }
+`
+	gomod := "module example.com/p\n\ngo 1.21\n"
+	if err := ioutil.WriteFile(filepath.Join(dir, "go.mod"), []byte(gomod), 0644); err != nil {
+		t.Fatal(err)
+	}
+	srcPath := filepath.Join(dir, "p.go")
+	if err := ioutil.WriteFile(srcPath, []byte(src), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	// Generated tests and examples must pass vet (run by go test).
+	testPath := filepath.Join(dir, "p_test.go")
+	opts := &generateOpts{diffOpts: &diffOpts{}, table: true, examples: true}
+	outFiles, err := goTestGenerate(srcPath, testPath, opts)
+	if err != nil {
+		t.Fatalf("goTestGenerate failed: %s", err)
+	}
+
+	res, err := outFiles[0].Generate()
+	if err != nil {
+		t.Fatalf("Generate failed: %s", err)
+	}
+	if err := ioutil.WriteFile(testPath, res, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	cmd := exec.Command(goCmd, "vet", ".")
+	cmd.Dir = dir
+	cmd.Env = append(os.Environ(), "GOTOOLCHAIN=local", "GOFLAGS=")
+	if out, err := cmd.CombinedOutput(); err != nil {
+		t.Errorf("go vet failed: %s\n%s\n%s", err, out, res)
+	}
+
+	// Only the int result of H is compared.
+	if n := strings.Count(string(res), "reflect.DeepEqual"); n != 1 {
+		t.Errorf("expected %d to eq %d", n, 1)
+	}
+
+	// Quicktest checker is not needed only for function results.
+	goFile, err := parse("p.go", strings.NewReader(src))
+	if err != nil {
+		t.Fatalf("parse failed: %s", err)
+	}
+	builder := &testBuilder{table: true, style: QuicktestAssert}
+	test, err := builder.build("TestF", funcTarget(goFile.Funcs[0]))
+	if err != nil {
+		t.Fatalf("build failed: %s", err)
+	}
+	if strings.Contains(string(test), "qt.New") {
+		t.Errorf("expected %q not to contain %q", test, "qt.New")
+	}
+}
@@ -0,0 +1,306 @@
+package main
+
+import (
+	"fmt"
+	"go/ast"
+	"go/types"
+	"strings"
+	"unicode"
+)
+
+// target is a function or method which generated code calls.
+type target struct {
+	// Name is the function or method name.
+	Name string
+
+	// RecvName is the receiver type name. It's empty for functions.
+	RecvName string
+
+	// RecvType is the receiver type used in generated code. It's
+	// instantiated if the receiver is generic type (e.g., `Stack[any]`),
+	// and qualified in external test package (`p.Stack[any]`).
+	RecvType string
+
+	// Pkg is the package name qualifying the function in external
+	// test package. It's empty otherwise.
+	Pkg string
+
+	// RecvVar is the variable name used for receiver.
+	RecvVar string
+
+	// ParamVars are the variable names used for parameters.
+	ParamVars []string
+
+	Signature *Signature
+
+	// Doc is the doc comment of the function or method.
+	Doc *ast.CommentGroup
+}
+
+// Var is a variable declared in generated code.
+type Var struct {
+	Name string
+	Type string
+
+	// Value is the initial value. Var is declared with zero value
+	// if it's empty.
+	Value string
+}
+
+// Decl returns the statement declaring the variable.
+func (v *Var) Decl() string {
+	if v.Value != "" {
+		return v.Name + " := " + v.Value
+	}
+	return "var " + v.Name + " " + v.Type
+}
+
+func funcTarget(fun *Func) *target {
+	return &target{
+		Name:      fun.Name,
+		Pkg:       fun.Pkg,
+		ParamVars: fun.Signature.ParamNames(),
+		Signature: fun.Signature,
+		Doc:       fun.Decl.Doc,
+	}
+}
+
+func methodTarget(method *Method) *target {
+	t := &target{
+		Name:      method.Name,
+		RecvName:  method.RecvName,
+		RecvType:  method.RecvName,
+		ParamVars: method.Signature.ParamNames(),
+		Signature: method.Signature,
+		Doc:       method.Decl.Doc,
+	}
+
+	if method.Pkg != "" {
+		t.RecvType = method.Pkg + "." + t.RecvType
+	}
+	if len(method.RecvTypeArgs) > 0 {
+		t.RecvType += "[" + joinArgs(method.RecvTypeArgs) + "]"
+	}
+
+	// Use receiver name in source if possible. Otherwise use the
+	// lower case of the first letter of receiver type name.
+	recv := method.Decl.Recv.List[0]
+	if len(recv.Names) == 1 && recv.Names[0].Name != "_" {
+		t.RecvVar = recv.Names[0].Name
+	} else {
+		t.RecvVar = receiverVar(method.RecvName, t.ParamVars)
+	}
+
+	return t
+}
+
+// receiverVar returns variable name for receiver type which
+// does not conflict with params.
+func receiverVar(recvName string, params []string) string {
+	name := "recv"
+	for _, r := range recvName {
+		name = string(unicode.ToLower(r))
+		break
+	}
+
+	if contains(params, name) {
+		return "recv"
+	}
+	return name
+}
+
+// reserve renames receiver and parameter variables which conflict
+// with the given names (e.g., `t` of *testing.T) used in generated code.
+func (t *target) reserve(names ...string) {
+	for i, name := range t.ParamVars {
+		if contains(names, name) {
+			t.ParamVars[i] = fmt.Sprintf("arg%d", i)
+		}
+	}
+
+	if t.IsMethod() && (contains(names, t.RecvVar) || contains(t.ParamVars, t.RecvVar)) {
+		t.RecvVar = "recv"
+	}
+}
+
+// IsMethod returns true if target is a method.
+func (t *target) IsMethod() bool {
+	return t.RecvName != ""
+}
+
+// Vars returns variables to be declared before calling target:
+// receiver (if method) and parameters. Function params get stubs
+// (see funcStub) and channel params get new channels.
+func (t *target) Vars() []*Var {
+	var vars []*Var
+	if t.IsMethod() {
+		vars = append(vars, &Var{Name: t.RecvVar, Type: t.RecvType})
+	}
+
+	for i, param := range t.Signature.Params {
+		vars = append(vars, paramVar(t.ParamVars[i], param))
+	}
+
+	return vars
+}
+
+// paramVar returns the variable for param.
+func paramVar(name string, param *Field) *Var {
+	v := &Var{Name: name, Type: param.VarType()}
+	switch x := param.Type.(type) {
+	case *ast.FuncType:
+		v.Value = funcStub(x)
+	case *ast.ChanType:
+		v.Value = "make(chan " + types.ExprString(x.Value) + ")"
+	}
+	return v
+}
+
+// ChanStmts returns statements to be run before calling target for
+// channel params created by Vars. Channels which target may receive
+// from are closed so that it does not block. Channels which target
+// only sends to are drained and closed after the call.
+func (t *target) ChanStmts() []string {
+	var stmts []string
+	for i, param := range t.Signature.Params {
+		if chanType, ok := param.Type.(*ast.ChanType); ok {
+			stmts = append(stmts, chanStmts(t.ParamVars[i], chanType)...)
+		}
+	}
+	return stmts
+}
+
+func chanStmts(name string, chanType *ast.ChanType) []string {
+	if chanType.Dir == ast.SEND {
+		return []string{
+			fmt.Sprintf("go func() {\nfor range %s {\n}\n}()", name),
+			fmt.Sprintf("defer close(%s)", name),
+		}
+	}
+	return []string{fmt.Sprintf("close(%s)", name)}
+}
+
+// Call returns the expression calling target with
+// parameter variables (e.g., `u.Delete(name)`).
+func (t *target) Call() string {
+	return t.callExpr(t.RecvVar, t.ParamVars)
+}
+
+// callExpr returns the expression calling target with the given
+// receiver and args expressions.
+func (t *target) callExpr(recv string, args []string) string {
+	argList := joinArgs(args)
+	if t.Signature.IsVariadic() {
+		argList += "..."
+	}
+
+	if t.IsMethod() {
+		return fmt.Sprintf("%s.%s(%s)", recv, t.Name, argList)
+	}
+
+	// Generic function is instantiated explicitly since
+	// type arguments may not be inferred from args.
+	name := t.Name
+	if t.Pkg != "" {
+		name = t.Pkg + "." + name
+	}
+	if len(t.Signature.TypeArgs) > 0 {
+		name += "[" + joinArgs(t.Signature.TypeArgs) + "]"
+	}
+	return fmt.Sprintf("%s(%s)", name, argList)
+}
+
+// FullName returns the name of target used in messages
+// (e.g., `User.Delete`).
+func (t *target) FullName() string {
+	if t.IsMethod() {
+		return t.RecvName + "." + t.Name
+	}
+	return t.Name
+}
+
+// Result is a result of target in generated test.
+type Result struct {
+	// Got is the variable name which the result is assigned to.
+	Got string
+
+	// Want is the field name of the expected value in test case.
+	Want string
+
+	Type string
+
+	// IsError is true if the result is the last error result.
+	// Test case has `wantErr bool` for it.
+	IsError bool
+
+	// Golden is true if the result is compared with golden file
+	// instead of the field of test case (see markGolden).
+	Golden bool
+
+	// IsFunc is true if the result is a function. Functions can't be
+	// compared (nor printed with %v), so it's only checked for nil.
+	IsFunc bool
+}
+
+// Results returns results of target. The last error result is
+// assigned to `err`. The others are assigned to their names in source
+// (and compared with `wantName` fields) if they are named. Otherwise,
+// or if the names are in reserved, they are `got`, `got1`, `got2`...
+func (t *target) Results(reserved ...string) []*Result {
+	var results []*Result
+	used := make(map[string]bool)
+	n := 0
+	for i, field := range t.Signature.Results {
+		typ := field.TypeString()
+		if i == len(t.Signature.Results)-1 && typ == "error" {
+			results = append(results, &Result{
+				Got:     "err",
+				Want:    "wantErr",
+				Type:    typ,
+				IsError: true,
+			})
+			continue
+		}
+
+		got, want := field.Name, "want"+strings.Title(field.Name)
+		for got == "" || got == "_" || contains(reserved, got) || used[got] {
+			suffix := ""
+			if n > 0 {
+				suffix = fmt.Sprintf("%d", n)
+			}
+			got, want = "got"+suffix, "want"+suffix
+			n++
+		}
+		used[got] = true
+
+		_, isFunc := field.Type.(*ast.FuncType)
+		results = append(results, &Result{
+			Got:    got,
+			Want:   want,
+			Type:   typ,
+			IsFunc: isFunc,
+		})
+	}
+
+	return results
+}
+
+// PrintsResults returns true if target returns any value and all of them
+// can be printed (functions can't be printed with fmt.Println).
+func (t *target) PrintsResults() bool {
+	for _, field := range t.Signature.Results {
+		if _, ok := field.Type.(*ast.FuncType); ok {
+			return false
+		}
+	}
+	return t.HasResults()
+}
+
+// HasResults returns true if target returns any value.
+func (t *target) HasResults() bool {
+	return len(t.Signature.Results) > 0
+}
+
+func joinArgs(args []string) string {
+	return strings.Join(args, ", ")
+}
@@ -0,0 +1,83 @@
+package main
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestTarget_Call(t *testing.T) {
+	src := `package p
+
+type User struct{}
+
+func NewUser(name string) *User { return nil }
+
+func (User) Delete(u string) error { return nil }
+`
+	goFile, err := parse("p.go", strings.NewReader(src))
+	if err != nil {
+		t.Fatalf("parse failed: %s", err)
+	}
+
+	if call := funcTarget(goFile.Funcs[0]).Call(); call != "NewUser(name)" {
+		t.Errorf("expected %q to eq %q", call, "NewUser(name)")
+	}
+
+	// Receiver variable must not conflict with params.
+	if call := methodTarget(goFile.Methods[0]).Call(); call != "recv.Delete(u)" {
+		t.Errorf("expected %q to eq %q", call, "recv.Delete(u)")
+	}
+}
+
+func TestTarget_params(t *testing.T) {
+	src := `package p
+
+type Stack[T any] struct{}
+
+func Each(fn func(int) error, ns ...int) error { return nil }
+
+func Send(in <-chan int, out chan<- int) {}
+
+func (s *Stack[T]) Push(v T) {}
+`
+	goFile, err := parse("p.go", strings.NewReader(src))
+	if err != nil {
+		t.Fatalf("parse failed: %s", err)
+	}
+
+	each := funcTarget(goFile.Funcs[0])
+	if call := each.Call(); call != "Each(fn, ns...)" {
+		t.Errorf("expected %q to eq %q", call, "Each(fn, ns...)")
+	}
+
+	var decls []string
+	for _, v := range each.Vars() {
+		decls = append(decls, v.Decl())
+	}
+	expected := []string{
+		"fn := func(int) (r0 error) {\n// TODO: Stub the function.\nreturn\n}",
+		"var ns []int",
+	}
+	if !reflect.DeepEqual(decls, expected) {
+		t.Errorf("expected %q to eq %q", decls, expected)
+	}
+
+	send := funcTarget(goFile.Funcs[1])
+	expected = []string{
+		"close(in)",
+		"go func() {\nfor range out {\n}\n}()",
+		"defer close(out)",
+	}
+	if stmts := send.ChanStmts(); !reflect.DeepEqual(stmts, expected) {
+		t.Errorf("expected %q to eq %q", stmts, expected)
+	}
+
+	push := methodTarget(goFile.Methods[0])
+	if push.RecvName != "Stack" || push.RecvType != "Stack[any]" {
+		t.Errorf("expected %q, %q to eq %q, %q", push.RecvName, push.RecvType, "Stack", "Stack[any]")
+	}
+	if typ := push.Signature.Params[0].TypeString(); typ != "any" {
+		t.Errorf("expected %q to eq %q", typ, "any")
+	}
+}